@@ -0,0 +1,369 @@
+package rtorrent
+
+import (
+	"encoding/base32"
+	"encoding/hex"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/autobrr/go-rtorrent/xmlrpc"
+
+	"github.com/pkg/errors"
+)
+
+// unexpectedType returns an error describing a value which did not have the expected shape
+func unexpectedType(field, expected string, v interface{}) error {
+	return errors.Errorf("%s: expected %s, got %T (%v)", field, expected, v, v)
+}
+
+// toInt64 converts an integer value decoded from XMLRPC into an int64,
+// accepting both int and int64 as produced by the decoder
+func toInt64(v interface{}) (int64, bool) {
+	switch n := v.(type) {
+	case int:
+		return int64(n), true
+	case int64:
+		return n, true
+	}
+	return 0, false
+}
+
+// toRatio converts the per-mille ratio reported by d.ratio into a float,
+// e.g. 1500 becomes 1.5
+func toRatio(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case int:
+		return float64(n) / 1000, true
+	case int64:
+		return float64(n) / 1000, true
+	case float64:
+		return n / 1000, true
+	}
+	return 0, false
+}
+
+// asSlice returns v as an array, nil is treated as an empty array
+func asSlice(field string, v interface{}) ([]interface{}, error) {
+	if v == nil {
+		return nil, nil
+	}
+	s, ok := v.([]interface{})
+	if !ok {
+		return nil, unexpectedType(field, "array", v)
+	}
+	return s, nil
+}
+
+// asRows returns the rows of a multicall result, some rTorrent versions answer an empty
+// multicall with a bare empty value instead of an array so any other value is treated as no rows
+func asRows(v interface{}) []interface{} {
+	rows, _ := v.([]interface{})
+	return rows
+}
+
+// asRow returns v as an array holding at least n values
+func asRow(field string, v interface{}, n int) ([]interface{}, error) {
+	row, err := asSlice(field, v)
+	if err != nil {
+		return nil, err
+	}
+	if len(row) < n {
+		return nil, errors.Errorf("%s: expected %d values, got %d", field, n, len(row))
+	}
+	return row, nil
+}
+
+// asString returns v as a string, nil is treated as an empty string
+func asString(field string, v interface{}) (string, error) {
+	if v == nil {
+		return "", nil
+	}
+	s, ok := v.(string)
+	if !ok {
+		return "", unexpectedType(field, "string", v)
+	}
+	return s, nil
+}
+
+// asInt64 returns v as an int64
+func asInt64(field string, v interface{}) (int64, error) {
+	n, ok := toInt64(v)
+	if !ok {
+		return 0, unexpectedType(field, "int", v)
+	}
+	return n, nil
+}
+
+// asInt returns v as an int
+func asInt(field string, v interface{}) (int, error) {
+	n, err := asInt64(field, v)
+	return int(n), err
+}
+
+// asRatio returns v as a ratio, see toRatio
+func asRatio(field string, v interface{}) (float64, error) {
+	ratio, ok := toRatio(v)
+	if !ok {
+		return 0, unexpectedType(field, "numeric ratio", v)
+	}
+	return ratio, nil
+}
+
+// asTime returns v, a unix timestamp, as a time.Time.
+// rTorrent reports 0 for timestamps which are not set, these become the zero time.Time.
+func asTime(field string, v interface{}) (time.Time, error) {
+	n, err := asInt64(field, v)
+	if err != nil || n == 0 {
+		return time.Time{}, err
+	}
+	return time.Unix(n, 0), nil
+}
+
+// asTimeString returns v, a unix timestamp stored as a string in a custom field, as a time.Time.
+// An empty value becomes the zero time.Time.
+func asTimeString(field string, v interface{}) (time.Time, error) {
+	str, err := asString(field, v)
+	if err != nil || str == "" {
+		return time.Time{}, err
+	}
+	n, err := strconv.ParseInt(strings.TrimSpace(str), 10, 64)
+	if err != nil {
+		return time.Time{}, unexpectedType(field, "unix timestamp", v)
+	}
+	return asTime(field, n)
+}
+
+// NormalizeHash returns the info-hash in the 40 character uppercase hex form used by rTorrent.
+// Both hex (any case) and base32 encoded hashes, as found in magnet links, are accepted;
+// anything else returns ErrInvalidHash.
+func NormalizeHash(hash string) (string, error) {
+	hash = strings.TrimSpace(hash)
+	switch len(hash) {
+	case hex.EncodedLen(20):
+		if _, err := hex.DecodeString(hash); err == nil {
+			return strings.ToUpper(hash), nil
+		}
+	case base32.StdEncoding.EncodedLen(20):
+		if b, err := base32.StdEncoding.DecodeString(strings.ToUpper(hash)); err == nil {
+			return strings.ToUpper(hex.EncodeToString(b)), nil
+		}
+	}
+	return "", errors.Wrapf(ErrInvalidHash, "%q", hash)
+}
+
+// isNotFound reports whether err is the fault rTorrent returns for an unknown info-hash
+func isNotFound(err error) bool {
+	var fault xmlrpc.Fault
+	if !errors.As(err, &fault) {
+		return false
+	}
+	return strings.Contains(fault.Message, "Could not find info-hash")
+}
+
+// isDuplicate reports whether err is the fault rTorrent returns when loading a torrent it already has
+func isDuplicate(err error) bool {
+	var fault xmlrpc.Fault
+	if !errors.As(err, &fault) {
+		return false
+	}
+	return strings.Contains(fault.Message, "Info hash already used by another torrent")
+}
+
+// isUnknownMethod reports whether err is the fault rTorrent returns for a command it doesn't define
+func isUnknownMethod(err error) bool {
+	var fault xmlrpc.Fault
+	if !errors.As(err, &fault) {
+		return false
+	}
+	return strings.Contains(fault.Message, "not defined")
+}
+
+// isUnknownView reports whether err is the fault rTorrent returns for a view it doesn't define, or ErrUnknownView
+func isUnknownView(err error) bool {
+	if errors.Is(err, ErrUnknownView) {
+		return true
+	}
+	var fault xmlrpc.Fault
+	if !errors.As(err, &fault) {
+		return false
+	}
+	return strings.Contains(fault.Message, "Could not find view")
+}
+
+// firstResult unwraps the single value of an XMLRPC call's results
+func firstResult(field string, results interface{}) (interface{}, error) {
+	values, err := asRow(field, results, 1)
+	if err != nil {
+		return nil, err
+	}
+	return values[0], nil
+}
+
+// resultString returns the single string value of an XMLRPC call's results
+func resultString(field string, results interface{}) (string, error) {
+	v, err := firstResult(field, results)
+	if err != nil {
+		return "", err
+	}
+	return asString(field, v)
+}
+
+// resultInt returns the single int value of an XMLRPC call's results
+func resultInt(field string, results interface{}) (int, error) {
+	v, err := firstResult(field, results)
+	if err != nil {
+		return 0, err
+	}
+	return asInt(field, v)
+}
+
+// resultInt64 returns the single int64 value of an XMLRPC call's results
+func resultInt64(field string, results interface{}) (int64, error) {
+	v, err := firstResult(field, results)
+	if err != nil {
+		return 0, err
+	}
+	return asInt64(field, v)
+}
+
+// resultRatio returns the single ratio value of an XMLRPC call's results
+func resultRatio(field string, results interface{}) (float64, error) {
+	v, err := firstResult(field, results)
+	if err != nil {
+		return 0, err
+	}
+	return asRatio(field, v)
+}
+
+// resultTime returns the single timestamp value of an XMLRPC call's results
+func resultTime(field string, results interface{}) (time.Time, error) {
+	v, err := firstResult(field, results)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return asTime(field, v)
+}
+
+// resultStrings unwraps the single array of strings of an XMLRPC call's results
+func resultStrings(field string, results interface{}) ([]string, error) {
+	v, err := firstResult(field, results)
+	if err != nil {
+		return nil, err
+	}
+	values, err := asSlice(field, v)
+	if err != nil {
+		return nil, err
+	}
+	strs := make([]string, 0, len(values))
+	for _, value := range values {
+		str, err := asString(field, value)
+		if err != nil {
+			return nil, err
+		}
+		strs = append(strs, str)
+	}
+	return strs, nil
+}
+
+// column maps a field fetched from rTorrent to the struct field it is decoded into
+type column struct {
+	field Field
+	index int
+}
+
+// structColumns returns the columns of the struct fields tagged `rtorrent:"<field>"`, in declaration order
+func structColumns(t reflect.Type) []column {
+	var columns []column
+	for i := 0; i < t.NumField(); i++ {
+		if field, ok := t.Field(i).Tag.Lookup("rtorrent"); ok {
+			columns = append(columns, column{field: Field(field), index: i})
+		}
+	}
+	return columns
+}
+
+// columnFields returns the fields of the columns
+func columnFields(columns []column) []Field {
+	fields := make([]Field, 0, len(columns))
+	for _, c := range columns {
+		fields = append(fields, c.field)
+	}
+	return fields
+}
+
+// timeType is the type of time.Time struct fields
+var timeType = reflect.TypeOf(time.Time{})
+
+// durationType is the type of time.Duration struct fields
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// decodeColumns decodes the row v, holding a value for each of the columns, into the struct dst
+func decodeColumns(name string, v interface{}, columns []column, dst reflect.Value) error {
+	row, err := asRow(name, v, len(columns))
+	if err != nil {
+		return err
+	}
+	for i, c := range columns {
+		if err := decodeColumn(c.field.Cmd(), row[i], dst.Field(c.index)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// decodeColumn decodes the value of field into dst, according to the type of dst:
+// times are unix timestamps, given as integers or strings for custom fields, floats are
+// ratios in permille, durations are seconds and booleans are set for any non-zero integer
+func decodeColumn(field string, v interface{}, dst reflect.Value) error {
+	if dst.Type() == timeType {
+		decode := asTime
+		if _, ok := v.(string); ok {
+			decode = asTimeString
+		}
+		t, err := decode(field, v)
+		if err != nil {
+			return err
+		}
+		dst.Set(reflect.ValueOf(t))
+		return nil
+	}
+	if dst.Type() == durationType {
+		n, err := asInt64(field, v)
+		if err != nil {
+			return err
+		}
+		dst.SetInt(int64(time.Duration(n) * time.Second))
+		return nil
+	}
+	switch dst.Kind() {
+	case reflect.String:
+		s, err := asString(field, v)
+		if err != nil {
+			return err
+		}
+		dst.SetString(s)
+	case reflect.Bool:
+		n, err := asInt64(field, v)
+		if err != nil {
+			return err
+		}
+		dst.SetBool(n != 0)
+	case reflect.Int, reflect.Int64:
+		n, err := asInt64(field, v)
+		if err != nil {
+			return err
+		}
+		dst.SetInt(n)
+	case reflect.Float64:
+		ratio, err := asRatio(field, v)
+		if err != nil {
+			return err
+		}
+		dst.SetFloat(ratio)
+	default:
+		return errors.Errorf("%s: cannot decode into %s", field, dst.Type())
+	}
+	return nil
+}
@@ -0,0 +1,156 @@
+package rtorrent
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestToRatio(t *testing.T) {
+	tests := []struct {
+		name  string
+		value interface{}
+		want  float64
+		ok    bool
+	}{
+		{name: "int", value: 1500, want: 1.5, ok: true},
+		{name: "int64", value: int64(123456789012), want: 123456789.012, ok: true},
+		{name: "float64", value: float64(250), want: 0.25, ok: true},
+		{name: "zero", value: 0, want: 0, ok: true},
+		{name: "string", value: "1500", want: 0, ok: false},
+		{name: "nil", value: nil, want: 0, ok: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := toRatio(tt.value)
+			require.Equal(t, tt.ok, ok)
+			require.InDelta(t, tt.want, got, 1e-9)
+		})
+	}
+}
+
+func TestDecodeTorrent(t *testing.T) {
+	row := torrentRow(Torrent{
+		Hash: "HASH", Name: "ubuntu.iso", Path: "/downloads", Size: 5665497088, Label: "label", Completed: true, Ratio: 1.5,
+		Created: time.Unix(1700000000, 0), Started: time.Unix(1700000100, 0), Priority: TorrentPriorityNormal, FileCount: 1,
+	})
+
+	t.Run("valid", func(t *testing.T) {
+		torrent, err := decodeTorrent(row)
+		require.NoError(t, err)
+		require.Equal(t, "HASH", torrent.Hash)
+		require.Equal(t, int64(5665497088), torrent.Size)
+		require.True(t, torrent.Completed)
+		require.Equal(t, 1.5, torrent.Ratio)
+		require.Equal(t, int64(1700000100), torrent.Started.Unix())
+		require.True(t, torrent.Finished.IsZero())
+		// without a recorded add time, the start time is used
+		require.Equal(t, torrent.Started, torrent.Added)
+		require.Equal(t, 1, torrent.FileCount)
+		require.True(t, torrent.StateChanged.IsZero())
+		require.False(t, torrent.IsPrivate)
+		require.Zero(t, torrent.UpTotal)
+	})
+
+	t.Run("totals", func(t *testing.T) {
+		totals := setColumn(setColumn(row, DUpTotal, int64(8498245632)), DDownTotal, 5665497088)
+		torrent, err := decodeTorrent(totals)
+		require.NoError(t, err)
+		require.Equal(t, int64(8498245632), torrent.UpTotal)
+		require.Equal(t, int64(5665497088), torrent.DownTotal)
+	})
+
+	t.Run("added", func(t *testing.T) {
+		added := setColumn(row, DAddedTime, "1700000050")
+		torrent, err := decodeTorrent(added)
+		require.NoError(t, err)
+		require.Equal(t, int64(1700000050), torrent.Added.Unix())
+	})
+
+	t.Run("not an array", func(t *testing.T) {
+		_, err := decodeTorrent("fault")
+		require.EqualError(t, err, "torrent: expected array, got string (fault)")
+	})
+
+	t.Run("truncated", func(t *testing.T) {
+		_, err := decodeTorrent(row[:5])
+		require.EqualError(t, err, "torrent: expected 21 values, got 5")
+	})
+
+	t.Run("wrong field type", func(t *testing.T) {
+		bad := setColumn(row, DHash, 42)
+		_, err := decodeTorrent(bad)
+		require.EqualError(t, err, "d.hash: expected string, got int (42)")
+	})
+}
+
+func TestDecodeColumns(t *testing.T) {
+	type row struct {
+		Name    string `rtorrent:"d.name"`
+		Skipped string
+		Active  bool      `rtorrent:"d.is_active"`
+		Added   time.Time `rtorrent:"d.custom=addtime"`
+		Ratio   float64   `rtorrent:"d.ratio"`
+	}
+	columns := structColumns(reflect.TypeOf(row{}))
+	require.Equal(t, []Field{DName, DIsActive, DAddedTime, DRatio}, columnFields(columns))
+
+	var r row
+	require.NoError(t, decodeColumns("row", []interface{}{"name", 1, "1700000000", 2500}, columns, reflect.ValueOf(&r).Elem()))
+	require.Equal(t, row{Name: "name", Active: true, Added: time.Unix(1700000000, 0), Ratio: 2.5}, r)
+
+	type unsupported struct {
+		Hashes []string `rtorrent:"d.hash"`
+	}
+	var u unsupported
+	err := decodeColumns("row", []interface{}{"HASH"}, structColumns(reflect.TypeOf(u)), reflect.ValueOf(&u).Elem())
+	require.EqualError(t, err, "d.hash: cannot decode into []string")
+}
+
+func TestDecodeFile(t *testing.T) {
+	file, err := decodeFile([]interface{}{"ubuntu/e01.mkv", 2048, 1024, 1, 4, 2, 6})
+	require.NoError(t, err)
+	require.Equal(t, File{Path: "ubuntu/e01.mkv", Size: 2048, Offset: 1024, CompletedChunks: 1, TotalChunks: 4, RangeFirst: 2, RangeSecond: 6}, file)
+	require.Equal(t, 25.0, file.PercentComplete())
+
+	_, err = decodeFile([]interface{}{"ubuntu/e01.mkv", 2048})
+	require.EqualError(t, err, "file: expected 7 values, got 2")
+}
+
+func TestResultString(t *testing.T) {
+	_, err := resultString("d.name", []interface{}{})
+	require.EqualError(t, err, "d.name: expected 1 values, got 0")
+
+	name, err := resultString("d.name", []interface{}{"ubuntu.iso"})
+	require.NoError(t, err)
+	require.Equal(t, "ubuntu.iso", name)
+
+	name, err = resultString("d.name", []interface{}{nil})
+	require.NoError(t, err)
+	require.Empty(t, name)
+
+	_, err = resultString("d.name", nil)
+	require.EqualError(t, err, "d.name: expected 1 values, got 0")
+}
+
+func TestNormalizeHash(t *testing.T) {
+	const want = "3F9AAC158C7DE8DFCAB171EA58A17AABDF7FBC93"
+	for _, hash := range []string{
+		"3F9AAC158C7DE8DFCAB171EA58A17AABDF7FBC93",
+		"3f9aac158c7de8dfcab171ea58a17aabdf7fbc93",
+		" 3f9aac158c7de8dfcab171ea58a17aabdf7fbc93\n",
+		"H6NKYFMMPXUN7SVROHVFRIL2VPPX7PET",
+		"h6nkyfmmpxun7svrohvfril2vppx7pet",
+	} {
+		got, err := NormalizeHash(hash)
+		require.NoError(t, err, hash)
+		require.Equal(t, want, got)
+	}
+
+	for _, hash := range []string{"", "HASH", "3F9AAC158C7DE8DFCAB171EA58A17AABDF7FBC9Z", want + "00"} {
+		_, err := NormalizeHash(hash)
+		require.ErrorIs(t, err, ErrInvalidHash, hash)
+	}
+}
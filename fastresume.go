@@ -0,0 +1,305 @@
+package rtorrent
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// ResumeFile is the fast-resume data of a single file of a torrent, see AddTorrentResumed
+type ResumeFile struct {
+	// MTime is the modification time of the complete file on the rTorrent host
+	MTime time.Time
+}
+
+// AddTorrentResumed adds a new torrent from the .torrent data, started and trusting that its files
+// are already complete instead of hash checking them. It is meant to move known-good torrents between
+// clients, the directory of the data is given with extraArgs as for AddTorrent, e.g. DDirectory.SetValue.
+//
+// The fast-resume data is a ResumeFile for each file of the torrent, in the order of the .torrent
+// (see GetFiles). rTorrent compares MTime to the modification time of the file on its host, to the
+// second: files which don't match are hash checked after all, so the data can't be trusted blindly.
+func (r *Client) AddTorrentResumed(ctx context.Context, data []byte, files []ResumeFile, extraArgs ...*FieldValue) error {
+	resumed, err := injectFastResume(data, files)
+	if err != nil {
+		return err
+	}
+	return r.AddTorrent(ctx, resumed, extraArgs...)
+}
+
+// BuildFastResume returns the .torrent data with fast-resume data for its files found in dataDir, so
+// rTorrent loads it without hash checking the files, see AddTorrentResumed. dataDir is the directory
+// the torrent is added with (see SetDirectory): a multi-file torrent is expected in dataDir/<name>.
+//
+// The files are only checked to exist with the size given by the torrent, their content is not hashed.
+// Their modification times must match the files seen by rTorrent, e.g. dataDir is the same filesystem
+// or a copy preserving modification times, otherwise rTorrent hash checks them after all.
+func BuildFastResume(torrentData []byte, dataDir string) ([]byte, error) {
+	layout, err := parseLayout(torrentData)
+	if err != nil {
+		return nil, err
+	}
+	files := make([]ResumeFile, 0, len(layout.paths))
+	for i, path := range layout.paths {
+		name := filepath.Join(append([]string{dataDir, layout.name}, path...)...)
+		fi, err := os.Stat(name)
+		if err != nil {
+			return nil, err
+		}
+		if !fi.Mode().IsRegular() {
+			return nil, errors.Errorf("%s is not a regular file", name)
+		}
+		if fi.Size() != layout.sizes[i] {
+			return nil, errors.Errorf("%s has %d bytes, the torrent expects %d", name, fi.Size(), layout.sizes[i])
+		}
+		files = append(files, ResumeFile{MTime: fi.ModTime()})
+	}
+	return injectFastResume(torrentData, files)
+}
+
+// validPathElem reports whether the file name of a torrent is safe to join to a directory
+func validPathElem(elem string) bool {
+	return elem != "" && elem != "." && elem != ".." && !strings.ContainsAny(elem, `/\`)
+}
+
+// torrentLayout is the layout of the data of a torrent, as described by its info dictionary
+type torrentLayout struct {
+	pieceLength int64
+	pieces      int64
+	// name is the name of the single file, or of the directory of a multi-file torrent
+	name string
+	// paths and sizes of the files, in the order of the torrent. The paths are relative
+	// to the directory of a multi-file torrent, and nil for a single-file torrent.
+	paths [][]string
+	sizes []int64
+}
+
+// injectFastResume returns the .torrent data with the libtorrent_resume dictionary marking all its chunks
+// done, as read by rTorrent when loading the torrent
+func injectFastResume(data []byte, files []ResumeFile) ([]byte, error) {
+	layout, err := parseLayout(data)
+	if err != nil {
+		return nil, err
+	}
+	if len(files) != len(layout.sizes) {
+		return nil, errors.Errorf("torrent has %d files, got resume data for %d", len(layout.sizes), len(files))
+	}
+
+	resumeFiles := make([]interface{}, 0, len(files))
+	var offset int64
+	for i, f := range files {
+		size := layout.sizes[i]
+		// the chunks overlapping the file, rTorrent checks them against the completed bitfield
+		var completed int64
+		if size > 0 {
+			completed = (offset+size-1)/layout.pieceLength - offset/layout.pieceLength + 1
+		}
+		offset += size
+		resumeFiles = append(resumeFiles, map[string]interface{}{
+			"completed": completed,
+			"mtime":     f.MTime.Unix(),
+			"priority":  int64(1),
+		})
+	}
+	resume := map[string]interface{}{
+		// an integer bitfield is the number of completed chunks, all chunks being done
+		"bitfield": layout.pieces,
+		"files":    resumeFiles,
+	}
+	var buf bytes.Buffer
+	bencode(&buf, resume)
+	return setTopLevelKey(data, "libtorrent_resume", buf.Bytes())
+}
+
+// parseLayout reads the layout of the torrent from its info dictionary
+func parseLayout(data []byte) (torrentLayout, error) {
+	var layout torrentLayout
+	v, end, err := bdecode(data, 0, 0)
+	if err != nil {
+		return layout, err
+	}
+	if end != len(data) {
+		return layout, errors.New("trailing data after the torrent dictionary")
+	}
+	torrent, ok := v.(map[string]interface{})
+	if !ok {
+		return layout, errors.New("torrent file is not a bencoded dictionary")
+	}
+	info, ok := torrent["info"].(map[string]interface{})
+	if !ok {
+		return layout, errors.New("torrent file has no info dictionary")
+	}
+	layout.name, _ = info["name"].(string)
+	if !validPathElem(layout.name) {
+		return layout, errors.Errorf("torrent file has an invalid name %q", layout.name)
+	}
+	layout.pieceLength, _ = info["piece length"].(int64)
+	pieces, _ := info["pieces"].(string)
+	if layout.pieceLength <= 0 || len(pieces) == 0 || len(pieces)%20 != 0 {
+		return layout, errors.New("torrent file has invalid pieces")
+	}
+	layout.pieces = int64(len(pieces) / 20)
+
+	if length, ok := info["length"].(int64); ok {
+		layout.paths = [][]string{nil}
+		layout.sizes = []int64{length}
+		return layout, nil
+	}
+	files, ok := info["files"].([]interface{})
+	if !ok || len(files) == 0 {
+		return layout, errors.New("torrent file has neither a length nor files")
+	}
+	for _, f := range files {
+		file, _ := f.(map[string]interface{})
+		length, ok := file["length"].(int64)
+		if !ok || length < 0 {
+			return layout, errors.New("torrent file has a file without a valid length")
+		}
+		elems, _ := file["path"].([]interface{})
+		path := make([]string, 0, len(elems))
+		for _, e := range elems {
+			elem, _ := e.(string)
+			if !validPathElem(elem) {
+				return layout, errors.Errorf("torrent file has an invalid file path %q", elems)
+			}
+			path = append(path, elem)
+		}
+		if len(path) == 0 {
+			return layout, errors.New("torrent file has a file without a path")
+		}
+		layout.paths = append(layout.paths, path)
+		layout.sizes = append(layout.sizes, length)
+	}
+	return layout, nil
+}
+
+// setTopLevelKey returns the bencoded dictionary data with key set to the bencoded value, replacing
+// the existing value if any. The other keys and values are kept byte for byte, so the info-hash is unchanged.
+func setTopLevelKey(data []byte, key string, value []byte) ([]byte, error) {
+	if len(data) == 0 || data[0] != 'd' {
+		return nil, errors.New("torrent file is not a bencoded dictionary")
+	}
+	encodedKey := []byte(strconv.Itoa(len(key)) + ":" + key)
+	out := make([]byte, 0, len(data)+len(encodedKey)+len(value))
+	out = append(out, 'd')
+	inserted := false
+	i := 1
+	for i < len(data) && data[i] != 'e' {
+		keyEnd, err := bencodeEnd(data, i)
+		if err != nil {
+			return nil, err
+		}
+		k, err := bencodeString(data[i:keyEnd])
+		if err != nil {
+			return nil, err
+		}
+		valueEnd, err := bencodeEnd(data, keyEnd)
+		if err != nil {
+			return nil, err
+		}
+		if !inserted && k >= key {
+			// keys are sorted, the new value goes before the first greater key
+			out = append(append(out, encodedKey...), value...)
+			inserted = true
+		}
+		if k != key {
+			out = append(out, data[i:valueEnd]...)
+		}
+		i = valueEnd
+	}
+	if i >= len(data) {
+		return nil, errors.New("truncated bencoded dictionary")
+	}
+	if !inserted {
+		out = append(append(out, encodedKey...), value...)
+	}
+	return append(out, data[i:]...), nil
+}
+
+// bdecode decodes the bencoded value starting at i, nested in depth lists and dictionaries, into
+// a string, int64, []interface{} or map[string]interface{}, and returns the position following it
+func bdecode(data []byte, i int, depth int) (interface{}, int, error) {
+	end, err := bencodeValueEnd(data, i, depth)
+	if err != nil {
+		return nil, 0, err
+	}
+	switch c := data[i]; {
+	case c == 'i':
+		n, err := strconv.ParseInt(string(data[i+1:end-1]), 10, 64)
+		if err != nil {
+			return nil, 0, errors.Errorf("invalid bencoded integer at %d", i)
+		}
+		return n, end, nil
+	case c == 'l':
+		list := []interface{}{}
+		for j := i + 1; j < end-1; {
+			v, next, err := bdecode(data, j, depth+1)
+			if err != nil {
+				return nil, 0, err
+			}
+			list = append(list, v)
+			j = next
+		}
+		return list, end, nil
+	case c == 'd':
+		dict := map[string]interface{}{}
+		for j := i + 1; j < end-1; {
+			k, next, err := bdecode(data, j, depth+1)
+			if err != nil {
+				return nil, 0, err
+			}
+			key, ok := k.(string)
+			if !ok {
+				return nil, 0, errors.New("bencoded dictionary key is not a string")
+			}
+			v, next, err := bdecode(data, next, depth+1)
+			if err != nil {
+				return nil, 0, err
+			}
+			dict[key] = v
+			j = next
+		}
+		return dict, end, nil
+	}
+	s, err := bencodeString(data[i:end])
+	return s, end, err
+}
+
+// bencode writes the bencoding of a string, int64, []interface{} or map[string]interface{} value
+func bencode(buf *bytes.Buffer, v interface{}) {
+	switch v := v.(type) {
+	case string:
+		buf.WriteString(strconv.Itoa(len(v)))
+		buf.WriteByte(':')
+		buf.WriteString(v)
+	case int64:
+		buf.WriteByte('i')
+		buf.WriteString(strconv.FormatInt(v, 10))
+		buf.WriteByte('e')
+	case []interface{}:
+		buf.WriteByte('l')
+		for _, e := range v {
+			bencode(buf, e)
+		}
+		buf.WriteByte('e')
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		buf.WriteByte('d')
+		for _, k := range keys {
+			bencode(buf, k)
+			bencode(buf, v[k])
+		}
+		buf.WriteByte('e')
+	}
+}
@@ -0,0 +1,157 @@
+package rtorrent
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// multiFileTorrent returns a .torrent of two files of 10 and 20 bytes, in chunks of 16 bytes
+func multiFileTorrent(extra map[string]interface{}) []byte {
+	torrent := map[string]interface{}{
+		"info": map[string]interface{}{
+			"name":         "show",
+			"piece length": int64(16),
+			"pieces":       strings.Repeat("x", 40),
+			"files": []interface{}{
+				map[string]interface{}{"length": int64(10), "path": []interface{}{"e01.mkv"}},
+				map[string]interface{}{"length": int64(20), "path": []interface{}{"extras", "e02.mkv"}},
+			},
+		},
+	}
+	for k, v := range extra {
+		torrent[k] = v
+	}
+	var buf bytes.Buffer
+	bencode(&buf, torrent)
+	return buf.Bytes()
+}
+
+// resumeOf returns the libtorrent_resume dictionary of the .torrent data
+func resumeOf(t *testing.T, data []byte) map[string]interface{} {
+	t.Helper()
+	v, _, err := bdecode(data, 0, 0)
+	require.NoError(t, err)
+	resume, ok := v.(map[string]interface{})["libtorrent_resume"].(map[string]interface{})
+	require.True(t, ok, "no libtorrent_resume dictionary")
+	return resume
+}
+
+func TestInjectFastResume(t *testing.T) {
+	mtime := time.Unix(1700000000, 0)
+
+	t.Run("single file", func(t *testing.T) {
+		data, err := os.ReadFile("testdata/ubuntu-24.10-desktop-amd64.iso.torrent")
+		require.NoError(t, err)
+
+		resumed, err := injectFastResume(data, []ResumeFile{{MTime: mtime}})
+		require.NoError(t, err)
+		hash, err := InfoHash(resumed)
+		require.NoError(t, err)
+		require.Equal(t, testHash, hash)
+
+		layout, err := parseLayout(data)
+		require.NoError(t, err)
+		require.Equal(t, map[string]interface{}{
+			"bitfield": layout.pieces,
+			"files":    []interface{}{map[string]interface{}{"completed": layout.pieces, "mtime": mtime.Unix(), "priority": int64(1)}},
+		}, resumeOf(t, resumed))
+	})
+
+	t.Run("multi file", func(t *testing.T) {
+		data := multiFileTorrent(map[string]interface{}{"announce": "http://tracker", "libtorrent_resume": "stale", "z": int64(1)})
+		hash, err := InfoHash(data)
+		require.NoError(t, err)
+
+		resumed, err := injectFastResume(data, []ResumeFile{{MTime: mtime}, {MTime: mtime.Add(time.Hour)}})
+		require.NoError(t, err)
+		resumedHash, err := InfoHash(resumed)
+		require.NoError(t, err)
+		require.Equal(t, hash, resumedHash)
+
+		// the first file is in chunk 0, the second one spans chunks 0 and 1
+		require.Equal(t, map[string]interface{}{
+			"bitfield": int64(2),
+			"files": []interface{}{
+				map[string]interface{}{"completed": int64(1), "mtime": mtime.Unix(), "priority": int64(1)},
+				map[string]interface{}{"completed": int64(2), "mtime": mtime.Add(time.Hour).Unix(), "priority": int64(1)},
+			},
+		}, resumeOf(t, resumed))
+
+		// the other keys are kept in order
+		v, _, err := bdecode(resumed, 0, 0)
+		require.NoError(t, err)
+		require.Equal(t, "http://tracker", v.(map[string]interface{})["announce"])
+		require.Equal(t, int64(1), v.(map[string]interface{})["z"])
+		require.Less(t, bytes.Index(resumed, []byte("17:libtorrent_resume")), bytes.Index(resumed, []byte("1:z")))
+	})
+
+	t.Run("file count mismatch", func(t *testing.T) {
+		_, err := injectFastResume(multiFileTorrent(nil), []ResumeFile{{MTime: mtime}})
+		require.EqualError(t, err, "torrent has 2 files, got resume data for 1")
+	})
+
+	t.Run("invalid torrent", func(t *testing.T) {
+		_, err := injectFastResume([]byte("d4:infod4:name3:fooee"), nil)
+		require.EqualError(t, err, "torrent file has invalid pieces")
+	})
+}
+
+func TestAddTorrentResumed(t *testing.T) {
+	var params []interface{}
+	client := newTestClient(t, map[string]interface{}{
+		"load.raw_start": func(p []interface{}) interface{} {
+			params = p
+			return 0
+		},
+	})
+
+	mtime := time.Unix(1700000000, 0)
+	err := client.AddTorrentResumed(context.Background(), multiFileTorrent(nil), []ResumeFile{{MTime: mtime}, {MTime: mtime}}, DDirectory.SetValue("/downloads"))
+	require.NoError(t, err)
+	require.Len(t, params, 3)
+	require.Equal(t, int64(2), resumeOf(t, params[1].([]byte))["bitfield"])
+	require.Equal(t, `d.directory.set="/downloads"`, params[2])
+}
+
+func TestBuildFastResume(t *testing.T) {
+	dir := t.TempDir()
+	mtime := time.Unix(1700000000, 0)
+	for name, size := range map[string]int{"show/e01.mkv": 10, "show/extras/e02.mkv": 20} {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		require.NoError(t, os.WriteFile(path, make([]byte, size), 0o644))
+		require.NoError(t, os.Chtimes(path, mtime, mtime))
+	}
+
+	resumed, err := BuildFastResume(multiFileTorrent(nil), dir)
+	require.NoError(t, err)
+	files := resumeOf(t, resumed)["files"].([]interface{})
+	require.Len(t, files, 2)
+	for _, f := range files {
+		require.Equal(t, mtime.Unix(), f.(map[string]interface{})["mtime"])
+	}
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "show", "e01.mkv"), make([]byte, 5), 0o644))
+	_, err = BuildFastResume(multiFileTorrent(nil), dir)
+	require.ErrorContains(t, err, "e01.mkv has 5 bytes, the torrent expects 10")
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "show", "e01.mkv"), make([]byte, 10), 0o644))
+	require.NoError(t, os.RemoveAll(filepath.Join(dir, "show", "extras")))
+	_, err = BuildFastResume(multiFileTorrent(nil), dir)
+	require.ErrorIs(t, err, os.ErrNotExist)
+
+	var buf bytes.Buffer
+	bencode(&buf, map[string]interface{}{"info": map[string]interface{}{
+		"name": "show", "piece length": int64(16), "pieces": strings.Repeat("x", 20),
+		"files": []interface{}{map[string]interface{}{"length": int64(1), "path": []interface{}{"..", "passwd"}}},
+	}})
+	_, err = BuildFastResume(buf.Bytes(), dir)
+	require.ErrorContains(t, err, "torrent file has an invalid file path")
+}
@@ -0,0 +1,176 @@
+package rtorrent
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// torrentJSON is the wire representation of a Torrent.
+// Sizes are encoded as strings so they survive JSON consumers limited to 2^53.
+type torrentJSON struct {
+	Hash      string     `json:"hash"`
+	Name      string     `json:"name"`
+	Path      string     `json:"path"`
+	Size      int64      `json:"size,string"`
+	Label     string     `json:"label"`
+	Completed bool       `json:"completed"`
+	Ratio     float64    `json:"ratio"`
+	Created   *time.Time `json:"created,omitempty"`
+	Added     *time.Time `json:"added,omitempty"`
+	Started   *time.Time `json:"started,omitempty"`
+	Finished  *time.Time `json:"finished,omitempty"`
+	Priority  int        `json:"priority"`
+
+	PeersConnected int `json:"peersConnected"`
+	Seeders        int `json:"seeders"`
+	Leechers       int `json:"leechers"`
+	FileCount      int `json:"fileCount"`
+
+	StateChanged *time.Time `json:"stateChanged,omitempty"`
+	StateCounter int        `json:"stateCounter"`
+	IsPrivate    bool       `json:"isPrivate"`
+
+	UpTotal   int64 `json:"upTotal,string"`
+	DownTotal int64 `json:"downTotal,string"`
+}
+
+// statusJSON is the wire representation of a Status
+type statusJSON struct {
+	Completed      bool    `json:"completed"`
+	CompletedBytes int64   `json:"completedBytes,string"`
+	DownRate       int     `json:"downRate"`
+	UpRate         int     `json:"upRate"`
+	Ratio          float64 `json:"ratio"`
+	Size           int64   `json:"size,string"`
+	ChunkSize      int64   `json:"chunkSize"`
+	ChunksTotal    int64   `json:"chunksTotal"`
+	ChunksDone     int64   `json:"chunksDone"`
+	BytesDone      int64   `json:"bytesDone,string"`
+
+	Hashing      HashingState `json:"hashing"`
+	ChunksHashed int64        `json:"chunksHashed"`
+}
+
+// fileJSON is the wire representation of a File
+type fileJSON struct {
+	Path            string `json:"path"`
+	Size            int64  `json:"size,string"`
+	Offset          int64  `json:"offset,string"`
+	CompletedChunks int64  `json:"completedChunks"`
+	TotalChunks     int64  `json:"totalChunks"`
+	RangeFirst      int64  `json:"rangeFirst"`
+	RangeSecond     int64  `json:"rangeSecond"`
+}
+
+// jsonTime returns the UTC time to encode, or nil if the time is unset
+func jsonTime(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	t = t.UTC()
+	return &t
+}
+
+// fromJSONTime returns the decoded time, or the zero time if it was omitted
+func fromJSONTime(t *time.Time) time.Time {
+	if t == nil {
+		return time.Time{}
+	}
+	return *t
+}
+
+// MarshalJSON encodes the Torrent with sizes as strings and timestamps in RFC3339 (UTC).
+// Unset timestamps are omitted.
+func (t Torrent) MarshalJSON() ([]byte, error) {
+	return json.Marshal(torrentJSON{
+		Hash:      t.Hash,
+		Name:      t.Name,
+		Path:      t.Path,
+		Size:      t.Size,
+		Label:     t.Label,
+		Completed: t.Completed,
+		Ratio:     t.Ratio,
+		Created:   jsonTime(t.Created),
+		Added:     jsonTime(t.Added),
+		Started:   jsonTime(t.Started),
+		Finished:  jsonTime(t.Finished),
+		Priority:  int(t.Priority),
+
+		PeersConnected: t.PeersConnected,
+		Seeders:        t.Seeders,
+		Leechers:       t.Leechers,
+		FileCount:      t.FileCount,
+
+		StateChanged: jsonTime(t.StateChanged),
+		StateCounter: t.StateCounter,
+		IsPrivate:    t.IsPrivate,
+
+		UpTotal:   t.UpTotal,
+		DownTotal: t.DownTotal,
+	})
+}
+
+// UnmarshalJSON decodes a Torrent encoded by MarshalJSON
+func (t *Torrent) UnmarshalJSON(data []byte) error {
+	var v torrentJSON
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	*t = Torrent{
+		Hash:      v.Hash,
+		Name:      v.Name,
+		Path:      v.Path,
+		Size:      v.Size,
+		Label:     v.Label,
+		Completed: v.Completed,
+		Ratio:     v.Ratio,
+		Created:   fromJSONTime(v.Created),
+		Added:     fromJSONTime(v.Added),
+		Started:   fromJSONTime(v.Started),
+		Finished:  fromJSONTime(v.Finished),
+		Priority:  TorrentPriority(v.Priority),
+
+		PeersConnected: v.PeersConnected,
+		Seeders:        v.Seeders,
+		Leechers:       v.Leechers,
+		FileCount:      v.FileCount,
+
+		StateChanged: fromJSONTime(v.StateChanged),
+		StateCounter: v.StateCounter,
+		IsPrivate:    v.IsPrivate,
+
+		UpTotal:   v.UpTotal,
+		DownTotal: v.DownTotal,
+	}
+	return nil
+}
+
+// MarshalJSON encodes the Status with byte counts as strings
+func (s Status) MarshalJSON() ([]byte, error) {
+	return json.Marshal(statusJSON(s))
+}
+
+// UnmarshalJSON decodes a Status encoded by MarshalJSON
+func (s *Status) UnmarshalJSON(data []byte) error {
+	var v statusJSON
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	*s = Status(v)
+	return nil
+}
+
+// MarshalJSON encodes the File with its size as a string
+func (f File) MarshalJSON() ([]byte, error) {
+	return json.Marshal(fileJSON(f))
+}
+
+// UnmarshalJSON decodes a File encoded by MarshalJSON
+func (f *File) UnmarshalJSON(data []byte) error {
+	var v fileJSON
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	*f = File(v)
+	return nil
+}
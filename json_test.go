@@ -0,0 +1,88 @@
+package rtorrent
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestJSON(t *testing.T) {
+	t.Run("torrent", func(t *testing.T) {
+		torrent := Torrent{
+			Hash:      "3F9AAC158C7DE8DFCAB171EA58A17AABDF7FBC93",
+			Name:      "ubuntu-24.10-desktop-amd64.iso",
+			Path:      "/downloads/temp",
+			Size:      5665497088,
+			Label:     "TestLabel",
+			Completed: true,
+			Ratio:     1.5,
+			Created:   time.Date(2024, 10, 10, 12, 0, 0, 0, time.FixedZone("CEST", 2*60*60)),
+			Priority:  TorrentPriorityHigh,
+
+			PeersConnected: 10,
+			Seeders:        4,
+			Leechers:       6,
+			FileCount:      1,
+			StateCounter:   3,
+			IsPrivate:      true,
+			UpTotal:        8498245632,
+			DownTotal:      5665497088,
+		}
+
+		b, err := json.Marshal(torrent)
+		require.NoError(t, err)
+		require.JSONEq(t, `{
+			"hash": "3F9AAC158C7DE8DFCAB171EA58A17AABDF7FBC93",
+			"name": "ubuntu-24.10-desktop-amd64.iso",
+			"path": "/downloads/temp",
+			"size": "5665497088",
+			"label": "TestLabel",
+			"completed": true,
+			"ratio": 1.5,
+			"created": "2024-10-10T10:00:00Z",
+			"priority": 3,
+			"peersConnected": 10,
+			"seeders": 4,
+			"leechers": 6,
+			"fileCount": 1,
+			"stateCounter": 3,
+			"isPrivate": true,
+			"upTotal": "8498245632",
+			"downTotal": "5665497088"
+		}`, string(b))
+
+		var decoded Torrent
+		require.NoError(t, json.Unmarshal(b, &decoded))
+		require.True(t, torrent.Created.Equal(decoded.Created))
+		require.True(t, decoded.Started.IsZero())
+		require.True(t, decoded.Finished.IsZero())
+		decoded.Created = torrent.Created
+		require.Equal(t, torrent, decoded)
+	})
+
+	t.Run("status", func(t *testing.T) {
+		status := Status{Completed: true, CompletedBytes: 1024, DownRate: 10, UpRate: 20, Ratio: 0.5, Size: 2048, ChunkSize: 512, ChunksTotal: 4, ChunksDone: 2, BytesDone: 1024, Hashing: HashingRehash, ChunksHashed: 1}
+
+		b, err := json.Marshal(status)
+		require.NoError(t, err)
+		require.JSONEq(t, `{"completed":true,"completedBytes":"1024","downRate":10,"upRate":20,"ratio":0.5,"size":"2048","chunkSize":512,"chunksTotal":4,"chunksDone":2,"bytesDone":"1024","hashing":3,"chunksHashed":1}`, string(b))
+
+		var decoded Status
+		require.NoError(t, json.Unmarshal(b, &decoded))
+		require.Equal(t, status, decoded)
+	})
+
+	t.Run("file", func(t *testing.T) {
+		file := File{Path: "ubuntu.iso", Size: 5665497088, Offset: 0, CompletedChunks: 10, TotalChunks: 21612, RangeFirst: 0, RangeSecond: 21612}
+
+		b, err := json.Marshal(file)
+		require.NoError(t, err)
+		require.JSONEq(t, `{"path":"ubuntu.iso","size":"5665497088","offset":"0","completedChunks":10,"totalChunks":21612,"rangeFirst":0,"rangeSecond":21612}`, string(b))
+
+		var decoded File
+		require.NoError(t, json.Unmarshal(b, &decoded))
+		require.Equal(t, file, decoded)
+	})
+}
@@ -0,0 +1,130 @@
+package rtorrent
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// InfoHash returns the info-hash of a .torrent file, in the form used by rTorrent
+func InfoHash(data []byte) (string, error) {
+	if len(data) == 0 || data[0] != 'd' {
+		return "", errors.New("torrent file is not a bencoded dictionary")
+	}
+	i := 1
+	for i < len(data) && data[i] != 'e' {
+		keyEnd, err := bencodeEnd(data, i)
+		if err != nil {
+			return "", err
+		}
+		key, err := bencodeString(data[i:keyEnd])
+		if err != nil {
+			return "", err
+		}
+		valueEnd, err := bencodeEnd(data, keyEnd)
+		if err != nil {
+			return "", err
+		}
+		if key == "info" {
+			sum := sha1.Sum(data[keyEnd:valueEnd])
+			return strings.ToUpper(hex.EncodeToString(sum[:])), nil
+		}
+		i = valueEnd
+	}
+	return "", errors.New("torrent file has no info dictionary")
+}
+
+// MagnetHash returns the info-hash of a magnet link, in the form used by rTorrent
+func MagnetHash(magnet string) (string, error) {
+	u, err := url.Parse(magnet)
+	if err != nil {
+		return "", errors.Wrap(err, "invalid magnet link")
+	}
+	if u.Scheme != "magnet" {
+		return "", errors.Errorf("invalid magnet link %q", magnet)
+	}
+	for _, xt := range u.Query()["xt"] {
+		if hash, ok := strings.CutPrefix(xt, "urn:btih:"); ok {
+			return NormalizeHash(hash)
+		}
+	}
+	return "", errors.Errorf("magnet link has no info-hash: %q", magnet)
+}
+
+// maxBencodeDepth is the deepest nesting of lists and dictionaries accepted in bencoded data.
+// The data of a .torrent comes from outside the program, deeper data is rejected rather than
+// recursing until the stack overflows.
+const maxBencodeDepth = 512
+
+// errBencodeTooDeep is returned for bencoded data nested deeper than maxBencodeDepth
+var errBencodeTooDeep = errors.Errorf("bencoded value nested deeper than %d levels", maxBencodeDepth)
+
+// bencodeEnd returns the position following the bencoded value starting at i
+func bencodeEnd(data []byte, i int) (int, error) {
+	return bencodeValueEnd(data, i, 0)
+}
+
+// bencodeValueEnd returns the position following the bencoded value starting at i,
+// nested in depth lists and dictionaries
+func bencodeValueEnd(data []byte, i int, depth int) (int, error) {
+	if i >= len(data) {
+		return 0, errors.New("truncated bencoded value")
+	}
+	switch c := data[i]; {
+	case c == 'i':
+		end := indexByte(data, i, 'e')
+		if end < 0 {
+			return 0, errors.New("truncated bencoded integer")
+		}
+		return end + 1, nil
+	case c == 'l' || c == 'd':
+		if depth >= maxBencodeDepth {
+			return 0, errBencodeTooDeep
+		}
+		i++
+		for i < len(data) && data[i] != 'e' {
+			end, err := bencodeValueEnd(data, i, depth+1)
+			if err != nil {
+				return 0, err
+			}
+			i = end
+		}
+		if i >= len(data) {
+			return 0, errors.New("truncated bencoded list")
+		}
+		return i + 1, nil
+	case c >= '0' && c <= '9':
+		colon := indexByte(data, i, ':')
+		if colon < 0 {
+			return 0, errors.New("truncated bencoded string")
+		}
+		n, err := strconv.Atoi(string(data[i:colon]))
+		if err != nil || n < 0 || n > len(data)-colon-1 {
+			return 0, errors.New("invalid bencoded string length")
+		}
+		return colon + 1 + n, nil
+	default:
+		return 0, errors.Errorf("invalid bencoded value at %d", i)
+	}
+}
+
+// bencodeString decodes a complete bencoded string
+func bencodeString(data []byte) (string, error) {
+	if len(data) == 0 || data[0] < '0' || data[0] > '9' {
+		return "", errors.New("bencoded dictionary key is not a string")
+	}
+	return string(data[indexByte(data, 0, ':')+1:]), nil
+}
+
+// indexByte returns the position of the first c in data from i, or -1
+func indexByte(data []byte, i int, c byte) int {
+	if n := bytes.IndexByte(data[i:], c); n >= 0 {
+		return i + n
+	}
+	return -1
+}
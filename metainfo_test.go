@@ -0,0 +1,52 @@
+package rtorrent
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestInfoHash(t *testing.T) {
+	data, err := os.ReadFile("testdata/ubuntu-24.10-desktop-amd64.iso.torrent")
+	require.NoError(t, err)
+
+	hash, err := InfoHash(data)
+	require.NoError(t, err)
+	require.Equal(t, testHash, hash)
+
+	_, err = InfoHash(data[:len(data)/2])
+	require.Error(t, err)
+	_, err = InfoHash([]byte("d8:announce3:fooe"))
+	require.EqualError(t, err, "torrent file has no info dictionary")
+
+	// a declared string length near MaxInt64 must not overflow the bounds check
+	for _, data := range []string{"d9223372036854775807:e", "d4:info9223372036854775807:e"} {
+		_, err = InfoHash([]byte(data))
+		require.EqualError(t, err, "invalid bencoded string length", data)
+	}
+	_, err = injectFastResume([]byte("d9223372036854775807:e"), nil)
+	require.EqualError(t, err, "invalid bencoded string length")
+
+	// deeply nested data is rejected instead of overflowing the stack
+	deep := []byte("d4:info" + strings.Repeat("l", 4<<20) + strings.Repeat("e", 4<<20) + "e")
+	_, err = InfoHash(deep)
+	require.EqualError(t, err, "bencoded value nested deeper than 512 levels")
+	_, err = injectFastResume(deep, nil)
+	require.EqualError(t, err, "bencoded value nested deeper than 512 levels")
+
+	// nesting up to the limit is accepted
+	nested := "d4:info" + strings.Repeat("l", maxBencodeDepth-1) + strings.Repeat("e", maxBencodeDepth-1) + "e"
+	_, err = InfoHash([]byte(nested))
+	require.NoError(t, err)
+}
+
+func TestMagnetHash(t *testing.T) {
+	hash, err := MagnetHash("magnet:?xt=urn:btih:3f9aac158c7de8dfcab171ea58a17aabdf7fbc93&dn=ubuntu")
+	require.NoError(t, err)
+	require.Equal(t, testHash, hash)
+
+	_, err = MagnetHash("https://example.com/ubuntu.torrent")
+	require.Error(t, err)
+}
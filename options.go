@@ -0,0 +1,110 @@
+package rtorrent
+
+import (
+	"crypto/tls"
+	"log/slog"
+	"net/url"
+	"time"
+)
+
+// WithBasicAuth authenticates calls with HTTP basic auth
+func WithBasicAuth(user, pass string) OptFunc {
+	return func(c *Client) {
+		c.cfg.BasicUser = user
+		c.cfg.BasicPass = pass
+	}
+}
+
+// WithBearerToken authenticates calls with an Authorization bearer token
+func WithBearerToken(token string) OptFunc {
+	return func(c *Client) {
+		c.cfg.BearerToken = token
+	}
+}
+
+// WithTLSSkipVerify disables the verification of the server certificate
+func WithTLSSkipVerify() OptFunc {
+	return func(c *Client) {
+		c.cfg.TLSSkipVerify = true
+	}
+}
+
+// WithClientCert authenticates the client with a certificate for mutual TLS
+func WithClientCert(cert tls.Certificate) OptFunc {
+	return func(c *Client) {
+		c.cfg.ClientCert = &cert
+	}
+}
+
+// WithTimeout bounds calls whose context has no deadline
+func WithTimeout(timeout time.Duration) OptFunc {
+	return func(c *Client) {
+		c.cfg.Timeout = timeout
+	}
+}
+
+// WithProxy sends calls through the proxy
+func WithProxy(proxy *url.URL) OptFunc {
+	return func(c *Client) {
+		c.cfg.Proxy = proxy
+	}
+}
+
+// WithUserAgent sets the User-Agent header of every call
+func WithUserAgent(userAgent string) OptFunc {
+	return func(c *Client) {
+		c.cfg.UserAgent = userAgent
+	}
+}
+
+// WithHeader adds a header sent with every call
+func WithHeader(key, value string) OptFunc {
+	return func(c *Client) {
+		if c.cfg.Headers == nil {
+			c.cfg.Headers = map[string]string{}
+		}
+		c.cfg.Headers[key] = value
+	}
+}
+
+// WithLabelDelimiter sets the delimiter separating the labels stored in d.custom1
+func WithLabelDelimiter(delimiter string) OptFunc {
+	return func(c *Client) {
+		c.cfg.LabelDelimiter = delimiter
+	}
+}
+
+// WithGzip compresses large requests and asks for compressed responses
+func WithGzip() OptFunc {
+	return func(c *Client) {
+		c.cfg.Gzip = true
+	}
+}
+
+// WithMaxConcurrentRequests bounds the calls in flight at once
+func WithMaxConcurrentRequests(n int) OptFunc {
+	return func(c *Client) {
+		c.cfg.MaxConcurrentRequests = n
+	}
+}
+
+// WithRetries sends calls again up to n times when they didn't reach rTorrent
+func WithRetries(n int) OptFunc {
+	return func(c *Client) {
+		c.cfg.Retries = n
+	}
+}
+
+// WithRecordAddTime stores when a torrent is added in its "addtime" custom field
+func WithRecordAddTime() OptFunc {
+	return func(c *Client) {
+		c.cfg.RecordAddTime = true
+	}
+}
+
+// WithLogger logs structured records of every call to logger
+func WithLogger(logger *slog.Logger) OptFunc {
+	return func(c *Client) {
+		c.cfg.Logger = logger
+	}
+}
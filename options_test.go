@@ -0,0 +1,74 @@
+package rtorrent
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewClientWithOpts(t *testing.T) {
+	var header http.Header
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header = r.Header
+		w.Header().Set("Content-Type", "text/xml")
+		_, _ = io.WriteString(w, `<?xml version="1.0"?><methodResponse><params><param><value><string>seedbox</string></value></param></params></methodResponse>`)
+	}))
+	t.Cleanup(srv.Close)
+
+	client := NewClientWithOpts(Config{Addr: srv.URL},
+		WithBasicAuth("user", "pass"),
+		WithUserAgent("my-app"),
+		WithHeader("X-Request-Source", "test"),
+		WithTimeout(time.Second),
+		WithRetries(2),
+	)
+	require.Equal(t, Config{
+		Addr:      srv.URL,
+		BasicUser: "user",
+		BasicPass: "pass",
+		UserAgent: "my-app",
+		Headers:   map[string]string{"X-Request-Source": "test"},
+		Timeout:   time.Second,
+		Retries:   2,
+	}, client.cfg)
+
+	name, err := client.Name(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, "seedbox", name)
+
+	user, pass, ok := (&http.Request{Header: header}).BasicAuth()
+	require.True(t, ok)
+	require.Equal(t, "user", user)
+	require.Equal(t, "pass", pass)
+	require.Equal(t, "my-app", header.Get("User-Agent"))
+	require.Equal(t, "test", header.Get("X-Request-Source"))
+}
+
+type countingTransport struct {
+	requests int
+}
+
+func (t *countingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.requests++
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+func TestWithCustomClient(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/xml")
+		_, _ = io.WriteString(w, `<?xml version="1.0"?><methodResponse><params><param><value><string>seedbox</string></value></param></params></methodResponse>`)
+	}))
+	t.Cleanup(srv.Close)
+
+	transport := &countingTransport{}
+	client := NewClientWithOpts(Config{Addr: srv.URL}, WithCustomClient(&http.Client{Transport: transport}), WithGzip())
+	_, err := client.Name(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, 1, transport.requests)
+	require.True(t, client.cfg.Gzip)
+}
@@ -0,0 +1,121 @@
+package rtorrent
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// stopRatioGroup prefixes the ratio groups created by SetStopRatio, followed by the ratio in percent
+const stopRatioGroup = "stop_ratio_"
+
+// SetStopRatio makes rTorrent close the torrent once it reached the ratio, 0 removes the stop ratio.
+//
+// rTorrent has no per-torrent stop ratio, only ratio groups: a group named stop_ratio_<percent> is created
+// for each ratio with group.insert_persistent_view, and configured with group2.<name>.ratio.min.set and
+// group2.<name>.ratio.max.set, see the ratio handling of rTorrent 0.9+. They take the ratio in percent,
+// e.g. ratio.min.set=200 for 2.0, so the ratio is rounded to a whole percent. The torrent joins the group
+// by being added to its view with d.views.push_back_unique and view.set_visible. rTorrent runs the default
+// ratio command of the group, which closes the torrent. The groups are not saved in the session: after a
+// restart the view of the torrent is restored, but the group only exists again once SetStopRatio recreates it.
+func (r *Client) SetStopRatio(ctx context.Context, t Torrent, ratio float64) error {
+	if ratio < 0 || math.IsNaN(ratio) || math.IsInf(ratio, 0) {
+		return errors.Errorf("invalid stop ratio %v", ratio)
+	}
+	percent := int(math.Round(ratio * 100))
+	group := fmt.Sprintf("%s%d", stopRatioGroup, percent)
+
+	views, err := r.torrentViews(ctx, t)
+	if err != nil {
+		return err
+	}
+	for _, view := range views {
+		if !strings.HasPrefix(view, stopRatioGroup) || (view == group && percent > 0) {
+			continue
+		}
+		if _, err := r.callHash(ctx, "d.views.remove", t.Hash, view); err != nil {
+			return errors.Wrap(err, "d.views.remove XMLRPC call failed")
+		}
+		if _, err := r.callHash(ctx, "view.set_not_visible", t.Hash, view); err != nil {
+			return errors.Wrap(err, "view.set_not_visible XMLRPC call failed")
+		}
+	}
+	if percent == 0 {
+		return nil
+	}
+
+	if err := r.ensureStopRatioGroup(ctx, group, percent); err != nil {
+		return err
+	}
+	if _, err := r.callHash(ctx, "d.views.push_back_unique", t.Hash, group); err != nil {
+		return errors.Wrap(err, "d.views.push_back_unique XMLRPC call failed")
+	}
+	if _, err := r.callHash(ctx, "view.set_visible", t.Hash, group); err != nil {
+		return errors.Wrap(err, "view.set_visible XMLRPC call failed")
+	}
+	return nil
+}
+
+// StopRatio returns the ratio set with SetStopRatio, 0 if the torrent has none
+func (r *Client) StopRatio(ctx context.Context, t Torrent) (float64, error) {
+	views, err := r.torrentViews(ctx, t)
+	if err != nil {
+		return 0, err
+	}
+	for _, view := range views {
+		if percent, ok := strings.CutPrefix(view, stopRatioGroup); ok {
+			n, err := strconv.Atoi(percent)
+			if err != nil {
+				return 0, errors.Errorf("invalid stop ratio view %q", view)
+			}
+			return float64(n) / 100, nil
+		}
+	}
+	return 0, nil
+}
+
+// torrentViews returns the views the torrent was added to
+func (r *Client) torrentViews(ctx context.Context, t Torrent) ([]string, error) {
+	results, err := r.callHash(ctx, "d.views", t.Hash)
+	if err != nil {
+		if isNotFound(err) {
+			return nil, errors.Wrap(ErrTorrentNotFound, t.Hash)
+		}
+		return nil, errors.Wrap(err, "d.views XMLRPC call failed")
+	}
+	return resultStrings("d.views", results)
+}
+
+// ensureStopRatioGroup creates the ratio group closing torrents at the ratio in percent, unless it exists
+func (r *Client) ensureStopRatioGroup(ctx context.Context, group string, percent int) error {
+	views, err := r.CallStringSlice(ctx, "view.list")
+	if err != nil {
+		return err
+	}
+	for _, view := range views {
+		if view == group {
+			return nil
+		}
+	}
+
+	calls := []struct {
+		method string
+		args   []interface{}
+	}{
+		{"group.insert_persistent_view", []interface{}{"", group}},
+		{"group2." + group + ".ratio.min.set", []interface{}{"", percent}},
+		{"group2." + group + ".ratio.max.set", []interface{}{"", percent}},
+		{"group2." + group + ".ratio.upload.set", []interface{}{"", 0}},
+		{"group2." + group + ".ratio.enable", []interface{}{""}},
+	}
+	for _, call := range calls {
+		if _, err := r.xmlrpcClient.Call(ctx, call.method, call.args...); err != nil {
+			return errors.Wrap(err, fmt.Sprintf("%s XMLRPC call failed", call.method))
+		}
+	}
+	return nil
+}
@@ -0,0 +1,86 @@
+package rtorrent
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestStopRatio(t *testing.T) {
+	torrentViews := []interface{}{"main"}
+	viewList := []interface{}{"main", "started"}
+	var groupCalls []string
+	group := func(method string) func(params []interface{}) interface{} {
+		return func(params []interface{}) interface{} {
+			groupCalls = append(groupCalls, method)
+			return 0
+		}
+	}
+	client := newTestClient(t, map[string]interface{}{
+		"d.views":   func() interface{} { return torrentViews },
+		"view.list": func() interface{} { return viewList },
+		"group.insert_persistent_view": func(params []interface{}) interface{} {
+			require.Equal(t, []interface{}{"", "stop_ratio_200"}, params)
+			viewList = append(viewList, params[1])
+			return 0
+		},
+		"group2.stop_ratio_200.ratio.min.set": func(params []interface{}) interface{} {
+			require.Equal(t, []interface{}{"", 200}, params)
+			groupCalls = append(groupCalls, "ratio.min.set")
+			return 0
+		},
+		"group2.stop_ratio_200.ratio.max.set":    group("ratio.max.set"),
+		"group2.stop_ratio_200.ratio.upload.set": group("ratio.upload.set"),
+		"group2.stop_ratio_200.ratio.enable":     group("ratio.enable"),
+		"d.views.push_back_unique": func(params []interface{}) interface{} {
+			for _, view := range torrentViews {
+				if view == params[1] {
+					return 0
+				}
+			}
+			torrentViews = append(torrentViews, params[1])
+			return 0
+		},
+		"d.views.remove": func(params []interface{}) interface{} {
+			for i, view := range torrentViews {
+				if view == params[1] {
+					torrentViews = append(torrentViews[:i], torrentViews[i+1:]...)
+					break
+				}
+			}
+			return 0
+		},
+		"view.set_visible":     0,
+		"view.set_not_visible": 0,
+	})
+	torrent := Torrent{Hash: testHash}
+
+	ratio, err := client.StopRatio(context.Background(), torrent)
+	require.NoError(t, err)
+	require.Zero(t, ratio)
+
+	require.NoError(t, client.SetStopRatio(context.Background(), torrent, 2))
+	require.Equal(t, []string{"ratio.min.set", "ratio.max.set", "ratio.upload.set", "ratio.enable"}, groupCalls)
+	require.Equal(t, []interface{}{"main", "stop_ratio_200"}, torrentViews)
+
+	ratio, err = client.StopRatio(context.Background(), torrent)
+	require.NoError(t, err)
+	require.Equal(t, 2.0, ratio)
+
+	// the group exists now, it is reused
+	require.NoError(t, client.SetStopRatio(context.Background(), torrent, 2))
+	require.Len(t, groupCalls, 4)
+	require.Equal(t, []interface{}{"main", "stop_ratio_200"}, torrentViews)
+
+	// the group names hold the ratio in percent, as rTorrent's ratio groups take it
+	torrentViews = []interface{}{"main", "stop_ratio_150"}
+	ratio, err = client.StopRatio(context.Background(), torrent)
+	require.NoError(t, err)
+	require.Equal(t, 1.5, ratio)
+
+	require.NoError(t, client.SetStopRatio(context.Background(), torrent, 0))
+	require.Equal(t, []interface{}{"main"}, torrentViews)
+
+	require.EqualError(t, client.SetStopRatio(context.Background(), torrent, -1), "invalid stop ratio -1")
+}
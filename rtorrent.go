@@ -1,11 +1,24 @@
 package rtorrent
 
 import (
+	"bytes"
 	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
 	"fmt"
 	"io"
 	"log"
+	"log/slog"
+	"math"
 	"net/http"
+	"net/url"
+	"path"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/autobrr/go-rtorrent/xmlrpc"
@@ -13,11 +26,28 @@ import (
 	"github.com/pkg/errors"
 )
 
-// Client is used to communicate with a remote rTorrent instance
+var (
+	// ErrTorrentNotFound is returned when rTorrent does not know the requested torrent
+	ErrTorrentNotFound = errors.New("torrent not found")
+	// ErrInvalidHash is returned when a torrent hash is not a valid info-hash, see NormalizeHash
+	ErrInvalidHash = errors.New("invalid info-hash")
+	// ErrTorrentExists is returned when adding a torrent which rTorrent already has loaded
+	ErrTorrentExists = errors.New("torrent already exists")
+	// ErrPrivateTorrent is returned when enabling PEX for a private torrent
+	ErrPrivateTorrent = errors.New("torrent is private")
+	// ErrUnknownView is returned when listing the torrents of a view rTorrent does not define
+	ErrUnknownView = errors.New("unknown view")
+)
+
+// Client is used to communicate with a remote rTorrent instance.
+// It is safe for concurrent use by multiple goroutines. A Client keeps connections open for reuse,
+// so it should be created once and reused rather than created for each request.
 type Client struct {
 	addr         string
 	xmlrpcClient *xmlrpc.Client
 	cfg          Config
+	// httpClient is the http.Client set by WithCustomClient, nil for the default one
+	httpClient *http.Client
 
 	log *log.Logger
 }
@@ -25,38 +55,107 @@ type Client struct {
 type Config struct {
 	Addr          string
 	TLSSkipVerify bool
+	// ClientCert and RootCAs configure mutual TLS, see the equivalent xmlrpc.Config fields
+	ClientCert *tls.Certificate
+	RootCAs    *x509.CertPool
 
 	BasicUser string
 	BasicPass string
+	// BearerToken and Authorize authenticate calls, see the equivalent xmlrpc.Config fields
+	BearerToken string
+	Authorize   func(req *http.Request) error
+
+	// UserAgent is sent as the User-Agent header of every call, defaults to xmlrpc.DefaultUserAgent
+	UserAgent string
+	// Headers are sent with every call, see xmlrpc.Config.Headers
+	Headers map[string]string
+
+	// Timeout bounds calls whose context has no deadline, defaults to xmlrpc.DefaultTimeout
+	Timeout time.Duration
+
+	// MaxIdleConns, MaxIdleConnsPerHost and IdleConnTimeout tune connection reuse,
+	// see the equivalent xmlrpc.Config fields for their defaults
+	MaxIdleConns        int
+	MaxIdleConnsPerHost int
+	IdleConnTimeout     time.Duration
+
+	// Proxy is the proxy calls go through, see xmlrpc.Config.Proxy
+	Proxy *url.URL
+
+	// RecordAddTime stores when a torrent is added in its "addtime" custom field, as ruTorrent does,
+	// see DAddedTime. rTorrent keeps no such timestamp itself.
+	RecordAddTime bool
+
+	// LabelDelimiter separates the labels stored in d.custom1 by GetLabels, AddLabel and RemoveLabel,
+	// defaults to DefaultLabelDelimiter
+	LabelDelimiter string
+
+	// Gzip compresses large requests, e.g. when adding torrents, and asks for compressed responses
+	Gzip bool
+
+	// MaxConcurrentRequests bounds the calls in flight at once, rTorrent handles
+	// XMLRPC calls one at a time. Zero means unbounded.
+	MaxConcurrentRequests int
+
+	// Retries is the number of times a call is sent again when it didn't reach rTorrent,
+	// see xmlrpc.Config.Retries
+	Retries int
 
 	Log *log.Logger
+	// Logger receives structured records of every call, it takes precedence over Log
+	Logger *slog.Logger
+	// Verbose additionally logs the full request and response XML of every call
+	Verbose bool
 }
 
+// OptFunc configures a Client created with NewClientWithOpts, see options.go for the available options
 type OptFunc func(*Client)
 
+// WithCustomClient makes calls with the http.Client instead of the default one,
+// the transport settings of the Config are ignored
 func WithCustomClient(client *http.Client) OptFunc {
 	return func(c *Client) {
-		c.xmlrpcClient = xmlrpc.NewClient(xmlrpc.Config{
-			Addr:          c.cfg.Addr,
-			TLSSkipVerify: c.cfg.TLSSkipVerify,
-			BasicUser:     c.cfg.BasicUser,
-			BasicPass:     c.cfg.BasicPass,
-			Client:        client,
-		})
+		c.httpClient = client
+	}
+}
+
+// xmlrpcConfig returns the configuration of the underlying XMLRPC client
+func (cfg Config) xmlrpcConfig() xmlrpc.Config {
+	return xmlrpc.Config{
+		Addr:          cfg.Addr,
+		TLSSkipVerify: cfg.TLSSkipVerify,
+		ClientCert:    cfg.ClientCert,
+		RootCAs:       cfg.RootCAs,
+		BasicUser:     cfg.BasicUser,
+		BasicPass:     cfg.BasicPass,
+		BearerToken:   cfg.BearerToken,
+		Authorize:     cfg.Authorize,
+		UserAgent:     cfg.UserAgent,
+		Headers:       cfg.Headers,
+		Timeout:       cfg.Timeout,
+
+		MaxIdleConns:        cfg.MaxIdleConns,
+		MaxIdleConnsPerHost: cfg.MaxIdleConnsPerHost,
+		IdleConnTimeout:     cfg.IdleConnTimeout,
+		Proxy:               cfg.Proxy,
+
+		Gzip:                  cfg.Gzip,
+		MaxConcurrentRequests: cfg.MaxConcurrentRequests,
+		Retries:               cfg.Retries,
+
+		Log:     cfg.Log,
+		Logger:  cfg.Logger,
+		Verbose: cfg.Verbose,
 	}
 }
 
 // NewClient returns a new instance of `Client`
 func NewClient(cfg Config) *Client {
 	c := &Client{
-		addr: cfg.Addr,
-		log:  log.New(io.Discard, "", log.LstdFlags),
-		xmlrpcClient: xmlrpc.NewClient(xmlrpc.Config{
-			Addr:          cfg.Addr,
-			TLSSkipVerify: cfg.TLSSkipVerify,
-			BasicUser:     cfg.BasicUser,
-			BasicPass:     cfg.BasicPass,
-		}),
+		addr:         cfg.Addr,
+		log:          log.New(io.Discard, "", log.LstdFlags),
+		cfg:          cfg,
+		xmlrpcClient: xmlrpc.NewClient(cfg.xmlrpcConfig()),
 	}
 
 	// override logger if we pass one
@@ -68,36 +167,56 @@ func NewClient(cfg Config) *Client {
 }
 
 // WithHTTPClient allows you to a provide a custom http.Client.
+// The basic auth credentials and logger of the Config are kept.
 func (r *Client) WithHTTPClient(client *http.Client) *Client {
-	r.xmlrpcClient = xmlrpc.NewClientWithHTTPClient(r.addr, client)
+	r.xmlrpcClient = xmlrpc.NewClientWithHTTPClient(r.cfg.xmlrpcConfig(), client)
 	return r
 }
 
+// NewClientWithOpts returns a new instance of `Client` like NewClient, with the settings of cfg
+// changed by opts, e.g. NewClientWithOpts(Config{Addr: addr}, WithBasicAuth(user, pass), WithRetries(3))
 func NewClientWithOpts(cfg Config, opts ...OptFunc) *Client {
 	c := &Client{
-		addr: cfg.Addr,
-		log:  log.New(io.Discard, "", log.LstdFlags),
-		cfg:  cfg,
-		xmlrpcClient: xmlrpc.NewClient(xmlrpc.Config{
-			Addr:          cfg.Addr,
-			TLSSkipVerify: cfg.TLSSkipVerify,
-			BasicUser:     cfg.BasicUser,
-			BasicPass:     cfg.BasicPass,
-		}),
+		log: log.New(io.Discard, "", log.LstdFlags),
+		cfg: cfg,
 	}
 
 	for _, opt := range opts {
 		opt(c)
 	}
 
+	c.addr = c.cfg.Addr
+	c.xmlrpcClient = xmlrpc.NewClientWithHTTPClient(c.cfg.xmlrpcConfig(), c.httpClient)
+
 	// override logger if we pass one
-	if cfg.Log != nil {
-		c.log = cfg.Log
+	if c.cfg.Log != nil {
+		c.log = c.cfg.Log
 	}
 
 	return c
 }
 
+// logError records an error of a task running in the background, such as Watch, which has no caller
+// to return it to. It goes to Config.Logger if set, like the records of the calls, or else to Config.Log.
+func (r *Client) logError(ctx context.Context, msg string, err error, attrs ...slog.Attr) {
+	if r.cfg.Logger == nil {
+		var b strings.Builder
+		b.WriteString(msg)
+		for _, attr := range attrs {
+			fmt.Fprintf(&b, " %s=%v", attr.Key, attr.Value)
+		}
+		r.log.Printf("%s: %v", b.String(), err)
+		return
+	}
+	r.cfg.Logger.LogAttrs(ctx, slog.LevelError, msg, append(attrs, slog.String("error", err.Error()))...)
+}
+
+// Close closes the idle connections kept open by the client, for clients which are discarded.
+// The client stays usable, calls made after Close open new connections.
+func (r *Client) Close() {
+	r.xmlrpcClient.Close()
+}
+
 // FieldValue contains the Field and Value of an attribute on a rTorrent
 type FieldValue struct {
 	Field Field
@@ -106,32 +225,210 @@ type FieldValue struct {
 
 // Torrent represents a torrent in rTorrent
 type Torrent struct {
-	Hash      string
-	Name      string
-	Path      string
-	Size      int
-	Label     string
-	Completed bool
-	Ratio     float64
-	Created   time.Time
-	Started   time.Time
-	Finished  time.Time
+	Hash      string  `rtorrent:"d.hash"`
+	Name      string  `rtorrent:"d.name"`
+	Path      string  `rtorrent:"d.directory"`
+	Size      int64   `rtorrent:"d.size_bytes"`
+	Label     string  `rtorrent:"d.custom1"`
+	Completed bool    `rtorrent:"d.complete"`
+	Ratio     float64 `rtorrent:"d.ratio"`
+	// Created, Added, Started and Finished are the zero time.Time when rTorrent has no
+	// timestamp, e.g. for a torrent which never finished; check them with IsZero.
+	// Created is when the .torrent was authored. Added is when it was added to rTorrent, as recorded
+	// by ruTorrent or Config.RecordAddTime (see DAddedTime), or else Started.
+	Created  time.Time       `rtorrent:"d.creation_date"`
+	Added    time.Time       `rtorrent:"d.custom=addtime"`
+	Started  time.Time       `rtorrent:"d.timestamp.started"`
+	Finished time.Time       `rtorrent:"d.timestamp.finished"`
+	Priority TorrentPriority `rtorrent:"d.priority"`
+	// PeersConnected is the number of peers connected to
+	PeersConnected int `rtorrent:"d.peers_connected"`
+	// Seeders is the number of connected peers which have the complete torrent
+	Seeders int `rtorrent:"d.peers_complete"`
+	// Leechers is the number of connected peers which are still downloading
+	Leechers int `rtorrent:"d.peers_accounted"`
+	// FileCount is the number of files in the torrent
+	FileCount int `rtorrent:"d.size_files"`
+	// StateChanged is when the torrent was last started or stopped, zero if never
+	StateChanged time.Time `rtorrent:"d.state_changed"`
+	// StateCounter is the number of times the torrent was started or stopped, a quickly
+	// rising counter means the torrent is flapping
+	StateCounter int `rtorrent:"d.state_counter"`
+	// IsPrivate reports whether the torrent is private, rTorrent never uses DHT or PEX for it
+	IsPrivate bool `rtorrent:"d.is_private"`
+	// UpTotal and DownTotal are the bytes uploaded and downloaded since the torrent was added
+	UpTotal   int64 `rtorrent:"d.up.total"`
+	DownTotal int64 `rtorrent:"d.down.total"`
+}
+
+// TorrentPriority represents the download priority of a torrent
+type TorrentPriority int
+
+const (
+	// TorrentPriorityOff means the torrent is not downloaded
+	TorrentPriorityOff TorrentPriority = 0
+	// TorrentPriorityLow is the low download priority
+	TorrentPriorityLow TorrentPriority = 1
+	// TorrentPriorityNormal is the default download priority
+	TorrentPriorityNormal TorrentPriority = 2
+	// TorrentPriorityHigh is the high download priority
+	TorrentPriorityHigh TorrentPriority = 3
+)
+
+func (p TorrentPriority) String() string {
+	switch p {
+	case TorrentPriorityOff:
+		return "off"
+	case TorrentPriorityLow:
+		return "low"
+	case TorrentPriorityNormal:
+		return "normal"
+	case TorrentPriorityHigh:
+		return "high"
+	}
+	return fmt.Sprintf("TorrentPriority(%d)", int(p))
+}
+
+// HashingState is the hash check a torrent is going through, as reported by d.hashing
+type HashingState int
+
+const (
+	// HashingNone means the torrent isn't being hash checked
+	HashingNone HashingState = 0
+	// HashingInitial is the first hash check of a newly loaded torrent
+	HashingInitial HashingState = 1
+	// HashingLast is the hash check of a torrent which finished downloading, with pieces.hash.on_completion enabled
+	HashingLast HashingState = 2
+	// HashingRehash is a hash check requested on a torrent which was checked before, see d.check_hash
+	HashingRehash HashingState = 3
+)
+
+// String returns the name of the hashing state
+func (h HashingState) String() string {
+	switch h {
+	case HashingNone:
+		return "none"
+	case HashingInitial:
+		return "initial"
+	case HashingLast:
+		return "last"
+	case HashingRehash:
+		return "rehash"
+	}
+	return fmt.Sprintf("HashingState(%d)", int(h))
 }
 
 // Status represents the status of a torrent
 type Status struct {
 	Completed      bool
-	CompletedBytes int
+	CompletedBytes int64
 	DownRate       int
 	UpRate         int
 	Ratio          float64
-	Size           int
+	Size           int64
+	// ChunkSize is the size in bytes of a single chunk
+	ChunkSize int64
+	// ChunksTotal is the number of chunks of the torrent
+	ChunksTotal int64
+	// ChunksDone is the number of chunks which have been downloaded
+	ChunksDone int64
+	// BytesDone is the number of bytes downloaded, unlike CompletedBytes it only
+	// accounts for the selected files
+	BytesDone int64
+	// Hashing is the hash check the torrent is going through, HashingNone if it isn't checked
+	Hashing HashingState
+	// ChunksHashed is the number of chunks checked by the current hash check
+	ChunksHashed int64
+}
+
+// HashProgress returns the share of chunks checked by the current hash check, from 0 to 1,
+// or 0 if the torrent isn't being checked
+func (s Status) HashProgress() float64 {
+	if s.Hashing == HashingNone || s.ChunksTotal <= 0 {
+		return 0
+	}
+	return float64(s.ChunksHashed) / float64(s.ChunksTotal)
 }
 
 // File represents a file in rTorrent
 type File struct {
 	Path string
-	Size int
+	Size int64
+	// Offset is the position in bytes of the file within the torrent
+	Offset int64
+	// CompletedChunks and TotalChunks are the number of chunks of the file downloaded and in total
+	CompletedChunks int64
+	TotalChunks     int64
+	// RangeFirst and RangeSecond are the first chunk of the file and the chunk following its last
+	RangeFirst  int64
+	RangeSecond int64
+}
+
+// Tracker represents a tracker of a torrent, see GetTrackers
+type Tracker struct {
+	URL     string `rtorrent:"t.url"`
+	Enabled bool   `rtorrent:"t.is_enabled"`
+	// Seeders and Leechers are the counts of the last scrape, see LastScrape
+	Seeders  int `rtorrent:"t.scrape_complete"`
+	Leechers int `rtorrent:"t.scrape_incomplete"`
+	// LastScrape is zero if the tracker has not been scraped yet
+	LastScrape time.Time `rtorrent:"t.scrape_time_last"`
+	// LastAnnounce is zero if the tracker has not been announced to yet
+	LastAnnounce time.Time `rtorrent:"t.activity_time_last"`
+	// NextAnnounce is when rTorrent announces to the tracker next, zero if none is scheduled,
+	// e.g. for a stopped torrent
+	NextAnnounce time.Time `rtorrent:"t.activity_time_next"`
+	// MinInterval is the minimum time between announces requested by the tracker, 0 if it sent none
+	MinInterval time.Duration `rtorrent:"t.min_interval"`
+	// Interval is the time between regular announces requested by the tracker
+	Interval time.Duration `rtorrent:"t.normal_interval"`
+	// SuccessCount and FailCount are the numbers of announces the tracker answered and failed
+	SuccessCount int `rtorrent:"t.success_counter"`
+	FailCount    int `rtorrent:"t.failed_counter"`
+	// LastEvent is the event of the latest request to the tracker. rTorrent keeps no message per
+	// tracker, the error of the last failed announce is the torrent's d.message.
+	LastEvent TrackerEvent `rtorrent:"t.latest_event"`
+}
+
+// TrackerEvent is the event sent with a request to a tracker, as reported by t.latest_event
+type TrackerEvent int
+
+const (
+	// TrackerEventNone is a regular announce, or no request was made yet
+	TrackerEventNone TrackerEvent = 0
+	// TrackerEventCompleted is the announce sent once the torrent finished downloading
+	TrackerEventCompleted TrackerEvent = 1
+	// TrackerEventStarted is the announce sent when the torrent is started
+	TrackerEventStarted TrackerEvent = 2
+	// TrackerEventStopped is the announce sent when the torrent is stopped
+	TrackerEventStopped TrackerEvent = 3
+	// TrackerEventScrape is a scrape request
+	TrackerEventScrape TrackerEvent = 4
+)
+
+func (e TrackerEvent) String() string {
+	switch e {
+	case TrackerEventNone:
+		return "none"
+	case TrackerEventCompleted:
+		return "completed"
+	case TrackerEventStarted:
+		return "started"
+	case TrackerEventStopped:
+		return "stopped"
+	case TrackerEventScrape:
+		return "scrape"
+	}
+	return fmt.Sprintf("TrackerEvent(%d)", int(e))
+}
+
+// EarliestAnnounce returns when the tracker honors an announce again, LastAnnounce plus MinInterval.
+// It is the zero time.Time if there is no such limit, so an announce can be made at any time.
+func (t Tracker) EarliestAnnounce() time.Time {
+	if t.LastAnnounce.IsZero() || t.MinInterval <= 0 {
+		return time.Time{}
+	}
+	return t.LastAnnounce.Add(t.MinInterval)
 }
 
 // Field represents an attribute on a Client entity that can be queried or set
@@ -151,6 +448,12 @@ const (
 	ViewHashing View = "hashing"
 	// ViewSeeding represents the "seeding" view, containing only torrents that are currently seeding
 	ViewSeeding View = "seeding"
+	// ViewComplete represents the "complete" view, containing only torrents that finished downloading.
+	// It isn't defined on every instance, see GetCompletedTorrents.
+	ViewComplete View = "complete"
+	// ViewIncomplete represents the "incomplete" view, containing only torrents that are not finished.
+	// It isn't defined on every instance, see GetIncompleteTorrents.
+	ViewIncomplete View = "incomplete"
 
 	// DName represents the name of a "Downloading Items"
 	DName Field = "d.name"
@@ -160,33 +463,121 @@ const (
 	DSizeInBytes Field = "d.size_bytes"
 	// DHash represents the hash of a "Downloading Item"
 	DHash Field = "d.hash"
-	// DBasePath represents the base path of a "Downloading Item"
+	// DBasePath represents the base path of a "Downloading Item": the data of a single-file torrent or
+	// the directory of a multi-file torrent. It is computed by rTorrent, empty while the torrent is closed,
+	// and cannot be set.
 	DBasePath Field = "d.base_path"
-	// DDirectory represents the directory of a "Downloading Item"
+	// DDirectory represents the directory of a "Downloading Item": the directory holding a single-file
+	// torrent, or the directory of a multi-file torrent including its name. See SetDirectory.
 	DDirectory Field = "d.directory"
+	// DDirectoryBase represents the directory of a "Downloading Item" as is, without the name of a
+	// multi-file torrent appended. See SetDirectoryBase.
+	DDirectoryBase Field = "d.directory_base"
 	// DIsActive represents whether a "Downloading Item" is active or not
 	DIsActive Field = "d.is_active"
+	// DIsOpen represents whether a "Downloading Item" is open, its files are closed otherwise
+	DIsOpen Field = "d.is_open"
+	// DState represents whether a "Downloading Item" is started (1) or stopped (0)
+	DState Field = "d.state"
 	// DRatio represents the ratio of a "Downloading Item"
 	DRatio Field = "d.ratio"
 	// DComplete represents whether the "Downloading Item" is complete or not
 	DComplete Field = "d.complete"
 	// DCompletedBytes represents the total of completed bytes of the "Downloading Item"
 	DCompletedBytes Field = "d.completed_bytes"
+	// DBytesDone represents the bytes downloaded of the selected files of the "Downloading Item"
+	DBytesDone Field = "d.bytes_done"
+	// DChunkSize represents the size in bytes of a chunk of the "Downloading Item"
+	DChunkSize Field = "d.chunk_size"
+	// DSizeChunks represents the number of chunks of the "Downloading Item"
+	DSizeChunks Field = "d.size_chunks"
+	// DCompletedChunks represents the number of completed chunks of the "Downloading Item"
+	DCompletedChunks Field = "d.completed_chunks"
+	// DHashing represents the hash check the "Downloading Item" is going through, see HashingState
+	DHashing Field = "d.hashing"
+	// DChunksHashed represents the number of chunks of the "Downloading Item" checked by the current hash check
+	DChunksHashed Field = "d.chunks_hashed"
 	// DDownRate represents the download rate of the "Downloading Item"
 	DDownRate Field = "d.down.rate"
 	// DUpRate represents the upload rate of the "Downloading Item"
 	DUpRate Field = "d.up.rate"
 	// DCreationTime represents the date the torrent was created
 	DCreationTime Field = "d.creation_date"
+	// DAddedTime represents the date the torrent was added to rTorrent, as recorded in the
+	// "addtime" custom field by ruTorrent, or by the Add methods of Client with Config.RecordAddTime.
+	// It is empty for torrents added by other means.
+	DAddedTime Field = "d.custom=addtime"
+	// DSeedingTime represents the date the torrent started seeding, as recorded in the
+	// "seedingtime" custom field by ruTorrent. It is empty for torrents not managed by ruTorrent.
+	DSeedingTime Field = "d.custom=seedingtime"
 	// DFinishedTime represents the date the torrent finished downloading
 	DFinishedTime Field = "d.timestamp.finished"
 	// DStartedTime represents the date the torrent started downloading
 	DStartedTime Field = "d.timestamp.started"
+	// DTiedToFile represents the path of the .torrent file the "Downloading Item" was loaded from
+	DTiedToFile Field = "d.tied_to_file"
+	// DPriority represents the download priority of the "Downloading Item", see TorrentPriority
+	DPriority Field = "d.priority"
+	// DIsMultiFile represents whether the "Downloading Item" contains multiple files
+	DIsMultiFile Field = "d.is_multi_file"
+	// DSizeFiles represents the number of files of the "Downloading Item"
+	DSizeFiles Field = "d.size_files"
+	// DStateChanged represents the date the "Downloading Item" was last started or stopped
+	DStateChanged Field = "d.state_changed"
+	// DStateCounter represents the number of times the "Downloading Item" was started or stopped
+	DStateCounter Field = "d.state_counter"
+	// DIsPrivate represents whether the "Downloading Item" is private
+	DIsPrivate Field = "d.is_private"
+	// DPeerExchange represents whether peer exchange (PEX) is enabled for the "Downloading Item"
+	DPeerExchange Field = "d.peer_exchange"
+	// DUpTotal represents the bytes uploaded of the "Downloading Item" since it was added
+	DUpTotal Field = "d.up.total"
+	// DDownTotal represents the bytes downloaded of the "Downloading Item" since it was added
+	DDownTotal Field = "d.down.total"
+	// DPeersConnected represents the number of peers connected to the "Downloading Item"
+	DPeersConnected Field = "d.peers_connected"
+	// DPeersComplete represents the number of connected seeders of the "Downloading Item"
+	DPeersComplete Field = "d.peers_complete"
+	// DPeersAccounted represents the number of connected leechers of the "Downloading Item"
+	DPeersAccounted Field = "d.peers_accounted"
 
 	// FPath represents the path of a "File Item"
 	FPath Field = "f.path"
 	// FSizeInBytes represents the size in bytes of a "File Item"
 	FSizeInBytes Field = "f.size_bytes"
+	// FOffset represents the offset in bytes of a "File Item" within its torrent
+	FOffset Field = "f.offset"
+	// FCompletedChunks represents the number of completed chunks of a "File Item"
+	FCompletedChunks Field = "f.completed_chunks"
+	// FSizeChunks represents the number of chunks of a "File Item"
+	FSizeChunks Field = "f.size_chunks"
+	// FRangeFirst represents the first chunk of a "File Item"
+	FRangeFirst Field = "f.range_first"
+	// FRangeSecond represents the chunk following the last chunk of a "File Item"
+	FRangeSecond Field = "f.range_second"
+
+	// TScrapeComplete represents the number of seeders last scraped from a "Tracker Item"
+	TScrapeComplete Field = "t.scrape_complete"
+	// TScrapeIncomplete represents the number of leechers last scraped from a "Tracker Item"
+	TScrapeIncomplete Field = "t.scrape_incomplete"
+	// TScrapeTimeLast represents the date a "Tracker Item" was last scraped, 0 if never
+	TScrapeTimeLast Field = "t.scrape_time_last"
+	// TURL represents the URL of a "Tracker Item"
+	TURL Field = "t.url"
+	// TIsEnabled represents whether a "Tracker Item" is enabled
+	TIsEnabled Field = "t.is_enabled"
+	// TActivityTimeLast represents the date a "Tracker Item" was last announced to, 0 if never
+	TActivityTimeLast Field = "t.activity_time_last"
+	// TActivityTimeNext represents the date a "Tracker Item" is announced to next, 0 if none is scheduled
+	TActivityTimeNext Field = "t.activity_time_next"
+	// TMinInterval represents the minimum seconds between announces requested by a "Tracker Item"
+	TMinInterval Field = "t.min_interval"
+	// TNormalInterval represents the seconds between regular announces requested by a "Tracker Item"
+	TNormalInterval Field = "t.normal_interval"
+	// TSuccessCounter represents the number of successful announces to a "Tracker Item"
+	TSuccessCounter Field = "t.success_counter"
+	// TFailedCounter represents the number of failed announces to a "Tracker Item"
+	TFailedCounter Field = "t.failed_counter"
 )
 
 // Query converts the field to a string which allows it to be queried
@@ -194,9 +585,22 @@ const (
 //
 //	DName.Query() // returns "d.name="
 func (f Field) Query() string {
+	if strings.Contains(string(f), "=") {
+		// the field already carries its argument, e.g. d.custom=addtime
+		return string(f)
+	}
 	return fmt.Sprintf("%s=", f)
 }
 
+// call returns the call querying the field of a single torrent
+func (f Field) call(hash string) xmlrpc.Call {
+	method, arg, ok := strings.Cut(string(f), "=")
+	if !ok {
+		return xmlrpc.Call{Method: method, Params: []interface{}{hash}}
+	}
+	return xmlrpc.Call{Method: method, Params: []interface{}{hash, arg}}
+}
+
 // SetValue returns a FieldValue struct which can be used to set the field on a particular item in rTorrent to the specified value
 func (f Field) SetValue(value string) *FieldValue {
 	return &FieldValue{f, value}
@@ -207,18 +611,78 @@ func (f Field) Cmd() string {
 	return string(f)
 }
 
+// String returns the command setting the field to the value, as passed to the load commands.
+// The value is quoted so it is taken literally, e.g. a directory containing quotes or backslashes.
 func (f *FieldValue) String() string {
-	return fmt.Sprintf("%s.set=\"%s\"", f.Field, f.Value)
+	return fmt.Sprintf("%s.set=%s", f.Field, quoteArg(f.Value))
 }
 
 // Pretty returns a formatted string representing this Torrent
 func (t *Torrent) Pretty() string {
-	return fmt.Sprintf("Torrent:\n\tHash: %v\n\tName: %v\n\tPath: %v\n\tLabel: %v\n\tSize: %v bytes\n\tCompleted: %v\n\tRatio: %v\n", t.Hash, t.Name, t.Path, t.Label, t.Size, t.Completed, t.Ratio)
+	return fmt.Sprintf("Torrent:\n\tHash: %v\n\tName: %v\n\tPath: %v\n\tLabel: %v\n\tSize: %v\n\tCompleted: %v\n\tRatio: %v\n", t.Hash, t.Name, t.Path, t.Label, FormatBytes(t.Size), t.Completed, t.Ratio)
+}
+
+// DownloadDuration returns the time the torrent took to download, from Started to Finished.
+// It reports false if either timestamp is unset or Finished precedes Started, e.g. when the
+// torrent was restarted after finishing.
+func (t Torrent) DownloadDuration() (time.Duration, bool) {
+	if t.Started.IsZero() || t.Finished.IsZero() || t.Finished.Before(t.Started) {
+		return 0, false
+	}
+	return t.Finished.Sub(t.Started), true
 }
 
 // Pretty returns a formatted string representing this File
 func (f *File) Pretty() string {
-	return fmt.Sprintf("File:\n\tPath: %v\n\tSize: %v bytes\n", f.Path, f.Size)
+	return fmt.Sprintf("File:\n\tPath: %v\n\tSize: %v\n", f.Path, FormatBytes(f.Size))
+}
+
+// PercentComplete returns the downloaded share of the file, from 0 to 100
+func (f File) PercentComplete() float64 {
+	if f.TotalChunks == 0 {
+		return 0
+	}
+	return float64(f.CompletedChunks) / float64(f.TotalChunks) * 100
+}
+
+// Pretty returns a formatted string representing this Status
+func (s *Status) Pretty() string {
+	return fmt.Sprintf("Status:\n\tCompleted: %v\n\tCompleted bytes: %v / %v\n\tDown rate: %v\n\tUp rate: %v\n\tRatio: %v\n", s.Completed, FormatBytes(s.CompletedBytes), FormatBytes(s.Size), FormatRate(int64(s.DownRate)), FormatRate(int64(s.UpRate)), s.Ratio)
+}
+
+// FormatBytes formats a number of bytes using IEC units, e.g. "5.3 GiB"
+func FormatBytes(b int64) string {
+	const unit = 1024
+	if b < unit && b > -unit {
+		return fmt.Sprintf("%d B", b)
+	}
+	div, exp := int64(unit), 0
+	for n := b / unit; n >= unit || n <= -unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(b)/float64(div), "KMGTPE"[exp])
+}
+
+// FormatRate formats a rate in bytes per second using IEC units, e.g. "2.1 MiB/s"
+func FormatRate(bytesPerSecond int64) string {
+	return FormatBytes(bytesPerSecond) + "/s"
+}
+
+// ETAUnknown is returned by Status.ETA when the torrent is not downloading
+const ETAUnknown = time.Duration(math.MaxInt64)
+
+// ETA returns the estimated time until the torrent completes at its current download rate.
+// It returns 0 for completed torrents and ETAUnknown when nothing is being downloaded.
+func (s Status) ETA() time.Duration {
+	remaining := s.Size - s.CompletedBytes
+	if s.Completed || remaining <= 0 {
+		return 0
+	}
+	if s.DownRate <= 0 {
+		return ETAUnknown
+	}
+	return time.Duration(remaining/int64(s.DownRate)) * time.Second
 }
 
 // AddStopped adds a new torrent by URL in a stopped state
@@ -287,7 +751,7 @@ func (r *Client) Add(ctx context.Context, url string, extraArgs ...*FieldValue)
 //
 //	AddTorrentStopped(fileData, DLabel.SetValue("my-label"), DBasePath.SetValue("/some/valid/path"))
 func (r *Client) AddTorrentStopped(ctx context.Context, data []byte, extraArgs ...*FieldValue) error {
-	return r.add(ctx, "load.raw", data, extraArgs...)
+	return r.AddTorrentReader(ctx, bytes.NewReader(data), false, extraArgs...)
 }
 
 // AddTorrent adds a new torrent by the torrent files data and starts the torrent
@@ -310,388 +774,2070 @@ func (r *Client) AddTorrentStopped(ctx context.Context, data []byte, extraArgs .
 //
 //	AddTorrent(fileData, DLabel.SetValue("my-label"), DBasePath.SetValue("/some/valid/path"))
 func (r *Client) AddTorrent(ctx context.Context, data []byte, extraArgs ...*FieldValue) error {
-	return r.add(ctx, "load.raw_start", data, extraArgs...)
+	return r.AddTorrentReader(ctx, bytes.NewReader(data), true, extraArgs...)
 }
 
-func (r *Client) add(ctx context.Context, cmd string, data []byte, extraArgs ...*FieldValue) error {
-	args := []interface{}{"", data}
-	for _, v := range extraArgs {
-		args = append(args, v.String())
+// AddTorrentReader adds a new torrent from the torrent file read from rd, starting it if start is true.
+// rd is read to the end and base64 encoded into the request while it is sent, so the torrent file is
+// never held in memory. The request is built in memory first when it may have to be sent again, with
+// Config.Gzip or Config.Retries, or is logged with Config.Verbose. Cancelling ctx aborts the upload in flight.
+//
+// extraArgs can be any valid rTorrent rpc command. For instance:
+//
+//	AddTorrentReader(resp.Body, true, DLabel.SetValue("my-label"))
+func (r *Client) AddTorrentReader(ctx context.Context, rd io.Reader, start bool, extraArgs ...*FieldValue) error {
+	cmd := "load.raw"
+	if start {
+		cmd = "load.raw_start"
 	}
+	return r.add(ctx, cmd, rd, extraArgs...)
+}
 
-	_, err := r.xmlrpcClient.Call(ctx, cmd, args...)
-	if err != nil {
-		return errors.Wrap(err, fmt.Sprintf("%s XMLRPC call failed", cmd))
+// AddWithDirectory adds a new torrent by URL which downloads into dir, starting it if start is true.
+// The directory is set with d.directory.set when the torrent is loaded, d.base_path is only
+// computed by rTorrent and cannot be used for this.
+//
+// extraArgs can be any valid rTorrent rpc command. For instance:
+//
+//	AddWithDirectory("some-url", "/downloads/movies", true, DLabel.SetValue("my-label"))
+func (r *Client) AddWithDirectory(ctx context.Context, url, dir string, start bool, extraArgs ...*FieldValue) error {
+	cmd := "load.normal"
+	if start {
+		cmd = "load.start"
 	}
-	return nil
+	args := append([]*FieldValue{DDirectory.SetValue(dir)}, extraArgs...)
+	return r.add(ctx, cmd, []byte(url), args...)
 }
 
-// IP returns the IP reported by this Client instance
-func (r *Client) IP(ctx context.Context) (string, error) {
-	result, err := r.xmlrpcClient.Call(ctx, "network.bind_address")
-	if err != nil {
-		return "", errors.Wrap(err, "network.bind_address XMLRPC call failed")
-	}
-	if ips, ok := result.([]interface{}); ok {
-		result = ips[0]
-	}
-	if ip, ok := result.(string); ok {
-		return ip, nil
+// AddFromPath adds a new torrent from a .torrent file located on the rTorrent host,
+// starting it if start is true. Unlike AddTorrent the file is not sent over the wire,
+// the path must be readable by rTorrent itself.
+//
+// extraArgs can be any valid rTorrent rpc command. For instance:
+//
+//	AddFromPath("/watch/some.torrent", true, DLabel.SetValue("my-label"))
+func (r *Client) AddFromPath(ctx context.Context, path string, start bool, extraArgs ...*FieldValue) error {
+	cmd := "load.normal"
+	if start {
+		cmd = "load.start"
 	}
-	return "", errors.Errorf("result isn't string: %v", result)
+	return r.add(ctx, cmd, []byte(path), extraArgs...)
 }
 
-// Name returns the name reported by this Client instance
-func (r *Client) Name(ctx context.Context) (string, error) {
-	result, err := r.xmlrpcClient.Call(ctx, "system.hostname")
-	if err != nil {
-		return "", errors.Wrap(err, "system.hostname XMLRPC call failed")
-	}
-	if names, ok := result.([]interface{}); ok {
-		result = names[0]
+// addWaitInterval is how often AddAndWait polls rTorrent for the added torrent
+var addWaitInterval = 250 * time.Millisecond
+
+// addTokenKey is the custom field AddAndWait tags a torrent added by URL with, to find it once loaded
+const addTokenKey = "add_token"
+
+// AddAndWait adds a new torrent by URL like Add, or stopped like AddStopped if start is false, and waits
+// until rTorrent has loaded it. rTorrent fetches the torrent of a URL in the background, so it is polled
+// for until ctx is done. A magnet link is found by its info-hash, any other URL by a token stored in a
+// custom field of the torrent, which needs d.multicall.filtered.
+func (r *Client) AddAndWait(ctx context.Context, url string, start bool, extraArgs ...*FieldValue) (Torrent, error) {
+	cmd := "load.normal"
+	if start {
+		cmd = "load.start"
 	}
-	if name, ok := result.(string); ok {
-		return name, nil
+	args := r.addArgs([]byte(url), extraArgs)
+	var hash, token string
+	if strings.HasPrefix(url, "magnet:") {
+		hash, _ = MagnetHash(url)
 	}
-	return "", errors.Errorf("result isn't string: %v", result)
-}
-
-// DownTotal returns the total downloaded metric reported by this Client instance (bytes)
-func (r *Client) DownTotal(ctx context.Context) (int, error) {
-	result, err := r.xmlrpcClient.Call(ctx, "throttle.global_down.total")
-	if err != nil {
-		return 0, errors.Wrap(err, "throttle.global_down.total XMLRPC call failed")
+	if hash == "" {
+		b := make([]byte, 16)
+		if _, err := rand.Read(b); err != nil {
+			return Torrent{}, errors.Wrap(err, "generating add token failed")
+		}
+		token = hex.EncodeToString(b)
+		args = append(args, fmt.Sprintf("d.custom.set=%s,%s", addTokenKey, token))
 	}
-	if totals, ok := result.([]interface{}); ok {
-		result = totals[0]
+	if _, err := r.xmlrpcClient.Call(ctx, cmd, args...); err != nil {
+		return Torrent{}, addError(cmd, err)
 	}
-	if total, ok := result.(int); ok {
-		return total, nil
+
+	ticker := time.NewTicker(addWaitInterval)
+	defer ticker.Stop()
+	for {
+		t, found, err := r.findAdded(ctx, hash, token)
+		if err != nil {
+			if ctx.Err() != nil {
+				return Torrent{}, ctx.Err()
+			}
+			return Torrent{}, err
+		}
+		if found {
+			return t, nil
+		}
+		select {
+		case <-ctx.Done():
+			return Torrent{}, ctx.Err()
+		case <-ticker.C:
+		}
 	}
-	return 0, errors.Errorf("result isn't int: %v", result)
 }
 
-// DownRate returns the current download rate reported by this Client instance (bytes/s)
-func (r *Client) DownRate(ctx context.Context) (int, error) {
-	result, err := r.xmlrpcClient.Call(ctx, "throttle.global_down.rate")
-	if err != nil {
-		return 0, errors.Wrap(err, "throttle.global_down.rate XMLRPC call failed")
+// findAdded looks up a torrent added by AddAndWait by its hash, or by its token if the hash is unknown.
+// The token is removed once the torrent is found.
+func (r *Client) findAdded(ctx context.Context, hash, token string) (Torrent, bool, error) {
+	if hash != "" {
+		t, err := r.GetTorrent(ctx, hash)
+		if errors.Is(err, ErrTorrentNotFound) {
+			return t, false, nil
+		}
+		return t, err == nil, err
 	}
-	if totals, ok := result.([]interface{}); ok {
-		result = totals[0]
+	filter := fmt.Sprintf("equal={%s,cat=%s}", Field("d.custom="+addTokenKey).Query(), quoteArg(token))
+	// ViewMain always exists, don't check it on every poll like GetTorrentsFiltered does
+	torrents, err := r.multicallTorrents(ctx, "d.multicall.filtered", "", string(ViewMain), filter)
+	if err != nil || len(torrents) == 0 {
+		return Torrent{}, false, err
 	}
-	if total, ok := result.(int); ok {
-		return total, nil
+	if err := r.SetCustom(ctx, torrents[0], addTokenKey, ""); err != nil {
+		return Torrent{}, false, err
 	}
-	return 0, errors.Errorf("result isn't int: %v", result)
+	return torrents[0], true, nil
 }
 
-// UpTotal returns the total uploaded metric reported by this Client instance (bytes)
-func (r *Client) UpTotal(ctx context.Context) (int, error) {
-	result, err := r.xmlrpcClient.Call(ctx, "throttle.global_up.total")
+// add loads a torrent with cmd from data, a []byte or an io.Reader. All the Add methods return ErrTorrentExists when
+// rTorrent reports the torrent is already loaded, so re-adding can be treated as a no-op.
+func (r *Client) add(ctx context.Context, cmd string, data interface{}, extraArgs ...*FieldValue) error {
+	_, err := r.xmlrpcClient.Call(ctx, cmd, r.addArgs(data, extraArgs)...)
 	if err != nil {
-		return 0, errors.Wrap(err, "throttle.global_up.total XMLRPC call failed")
+		return addError(cmd, err)
 	}
-	if totals, ok := result.([]interface{}); ok {
-		result = totals[0]
+	return nil
+}
+
+// addArgs returns the arguments of a load command
+func (r *Client) addArgs(data interface{}, extraArgs []*FieldValue) []interface{} {
+	args := []interface{}{"", data}
+	if r.cfg.RecordAddTime {
+		// the same way ruTorrent does, see DAddedTime
+		args = append(args, fmt.Sprintf("d.custom.set=addtime,%d", time.Now().Unix()))
 	}
-	if total, ok := result.(int); ok {
-		return total, nil
+	for _, v := range extraArgs {
+		args = append(args, v.String())
 	}
-	return 0, errors.Errorf("result isn't int: %v", result)
+	return args
 }
 
-// UpRate returns the current upload rate reported by this Client instance (bytes/s)
-func (r *Client) UpRate(ctx context.Context) (int, error) {
-	result, err := r.xmlrpcClient.Call(ctx, "throttle.global_up.rate")
-	if err != nil {
-		return 0, errors.Wrap(err, "throttle.global_up.rate XMLRPC call failed")
+// addError wraps the error of a load command
+func addError(cmd string, err error) error {
+	if isDuplicate(err) {
+		return errors.Wrap(ErrTorrentExists, err.Error())
 	}
-	if totals, ok := result.([]interface{}); ok {
-		result = totals[0]
+	return errors.Wrap(err, fmt.Sprintf("%s XMLRPC call failed", cmd))
+}
+
+// AddItem describes a torrent to add with AddTorrents, by URL or by the data of its torrent file
+type AddItem struct {
+	// URL of the torrent or magnet link, used when Data is empty
+	URL string
+	// Data of the torrent file
+	Data []byte
+	// Start starts the torrent once added
+	Start bool
+	// ExtraArgs are applied to the torrent when it is loaded, see Add
+	ExtraArgs []*FieldValue
+}
+
+// AddResult reports the outcome of adding an AddItem
+type AddResult struct {
+	// Hash is the info-hash of the torrent, empty if it could not be determined
+	// from the torrent file or magnet link, e.g. for a URL of a torrent file
+	Hash string
+	// Err is the error adding the torrent, ErrTorrentExists if it was already loaded
+	Err error
+}
+
+// AddTorrents adds the torrents in a single system.multicall. The results are in the
+// order of items and report the error of each item, so one bad torrent doesn't abort the batch.
+// The returned error is only set if the batch as a whole failed.
+func (r *Client) AddTorrents(ctx context.Context, items []AddItem) ([]AddResult, error) {
+	results := make([]AddResult, len(items))
+	calls := make([]xmlrpc.Call, 0, len(items))
+	for i, item := range items {
+		cmd, data := "load.normal", interface{}([]byte(item.URL))
+		if item.Start {
+			cmd = "load.start"
+		}
+		if len(item.Data) > 0 {
+			cmd, data = "load.raw", item.Data
+			if item.Start {
+				cmd = "load.raw_start"
+			}
+			results[i].Hash, _ = InfoHash(item.Data)
+		} else if strings.HasPrefix(item.URL, "magnet:") {
+			results[i].Hash, _ = MagnetHash(item.URL)
+		}
+		calls = append(calls, xmlrpc.Call{Method: cmd, Params: r.addArgs(data, item.ExtraArgs)})
 	}
-	if total, ok := result.(int); ok {
-		return total, nil
+	if len(calls) == 0 {
+		return results, nil
 	}
-	return 0, errors.Errorf("result isn't int: %v", result)
-}
 
-// GetTorrents returns all the torrents reported by this Client instance
-func (r *Client) GetTorrents(ctx context.Context, view View) ([]Torrent, error) {
-	args := []interface{}{"", string(view), DName.Query(), DSizeInBytes.Query(), DHash.Query(), DLabel.Query(), DDirectory.Query(), DIsActive.Query(), DComplete.Query(), DRatio.Query(), DCreationTime.Query(), DFinishedTime.Query(), DStartedTime.Query()}
-	results, err := r.xmlrpcClient.Call(ctx, "d.multicall2", args...)
-	var torrents []Torrent
+	values, err := r.xmlrpcClient.MulticallBatch(ctx, calls)
 	if err != nil {
-		return torrents, errors.Wrap(err, "d.multicall2 XMLRPC call failed")
-	}
-	for _, outerResult := range results.([]interface{}) {
-		for _, innerResult := range outerResult.([]interface{}) {
-			torrentData := innerResult.([]interface{})
-			torrents = append(torrents, Torrent{
-				Hash:      torrentData[2].(string),
-				Name:      torrentData[0].(string),
-				Path:      torrentData[4].(string),
-				Size:      torrentData[1].(int),
-				Label:     torrentData[3].(string),
-				Completed: torrentData[6].(int) > 0,
-				Ratio:     float64(torrentData[7].(int)) / float64(1000),
-				Created:   time.Unix(int64(torrentData[8].(int)), 0),
-				Finished:  time.Unix(int64(torrentData[9].(int)), 0),
-				Started:   time.Unix(int64(torrentData[10].(int)), 0),
-			})
+		return nil, err
+	}
+	for i, v := range values {
+		if fault, ok := v.(xmlrpc.Fault); ok {
+			results[i].Err = addError(calls[i].Method, fault)
 		}
 	}
-	return torrents, nil
+	return results, nil
 }
 
-// GetTorrent returns the torrent identified by the given hash
-func (r *Client) GetTorrent(ctx context.Context, hash string) (Torrent, error) {
-	var t Torrent
-	t.Hash = hash
-	// Name
-	results, err := r.xmlrpcClient.Call(ctx, "d.name", t.Hash)
+// AddTorrentWithFileSelection adds the torrent from the data of its torrent file, downloading only the files
+// at the selected indices, in the order returned by GetFiles. The file list is only known once rTorrent
+// has loaded the torrent, so it is added stopped, the other files are set to off priority and the torrent
+// is then started if start is true. If selecting the files fails the torrent is left loaded and stopped.
+func (r *Client) AddTorrentWithFileSelection(ctx context.Context, data []byte, selected []int, start bool) error {
+	hash, err := InfoHash(data)
 	if err != nil {
-		return t, errors.Wrap(err, "d.name XMLRPC call failed")
+		return err
 	}
-	t.Name = results.([]interface{})[0].(string)
-	// Size
-	results, err = r.xmlrpcClient.Call(ctx, "d.size_bytes", t.Hash)
-	if err != nil {
-		return t, errors.Wrap(err, "d.size_bytes XMLRPC call failed")
+	for _, i := range selected {
+		if i < 0 {
+			return errors.Errorf("invalid file index %d", i)
+		}
 	}
-	t.Size = results.([]interface{})[0].(int)
-	// Label
-	results, err = r.xmlrpcClient.Call(ctx, "d.custom1", t.Hash)
-	if err != nil {
-		return t, errors.Wrap(err, "d.custom1 XMLRPC call failed")
+	if err := r.AddTorrentStopped(ctx, data); err != nil {
+		return err
 	}
-	t.Label = results.([]interface{})[0].(string)
-	// Path
-	results, err = r.xmlrpcClient.Call(ctx, "d.directory", t.Hash)
+	t := Torrent{Hash: hash}
+	files, err := r.GetFiles(ctx, t)
 	if err != nil {
-		return t, errors.Wrap(err, "d.directory XMLRPC call failed")
+		return err
 	}
-	t.Path = results.([]interface{})[0].(string)
-	// Completed
-	results, err = r.xmlrpcClient.Call(ctx, "d.complete", t.Hash)
-	if err != nil {
-		return t, errors.Wrap(err, "d.complete XMLRPC call failed")
+	keep := make(map[int]bool, len(selected))
+	for _, i := range selected {
+		if i >= len(files) {
+			return errors.Errorf("invalid file index %d, the torrent has %d files", i, len(files))
+		}
+		keep[i] = true
 	}
-	t.Completed = results.([]interface{})[0].(int) > 0
-	// Ratio
-	results, err = r.xmlrpcClient.Call(ctx, "d.ratio", t.Hash)
-	if err != nil {
-		return t, errors.Wrap(err, "d.ratio XMLRPC call failed")
+
+	calls := make([]xmlrpc.Call, 0, len(files)+1)
+	for i := range files {
+		if !keep[i] {
+			// 0 is off, the file is not downloaded
+			calls = append(calls, xmlrpc.Call{Method: "f.priority.set", Params: []interface{}{fmt.Sprintf("%s:f%d", hash, i), 0}})
+		}
 	}
-	t.Ratio = float64(results.([]interface{})[0].(int)) / float64(1000)
-	// Created
-	results, err = r.xmlrpcClient.Call(ctx, string(DCreationTime), t.Hash)
+	calls = append(calls, xmlrpc.Call{Method: "d.update_priorities", Params: []interface{}{hash}})
+	values, err := r.xmlrpcClient.MulticallBatch(ctx, calls)
 	if err != nil {
-		return t, errors.Wrap(err, fmt.Sprintf("%s XMLRPC call failed", string(DCreationTime)))
+		return errors.Wrap(err, "f.priority.set XMLRPC call failed")
 	}
-	t.Created = time.Unix(int64(results.([]interface{})[0].(int)), 0)
-	// Finished
-	results, err = r.xmlrpcClient.Call(ctx, string(DFinishedTime), t.Hash)
-	if err != nil {
-		return t, errors.Wrap(err, fmt.Sprintf("%s XMLRPC call failed", string(DFinishedTime)))
+	for i, v := range values {
+		if fault, ok := v.(xmlrpc.Fault); ok {
+			return errors.Wrap(fault, fmt.Sprintf("%s XMLRPC call failed", calls[i].Method))
+		}
 	}
-	t.Finished = time.Unix(int64(results.([]interface{})[0].(int)), 0)
-	// Started
-	results, err = r.xmlrpcClient.Call(ctx, string(DStartedTime), t.Hash)
-	if err != nil {
-		return t, errors.Wrap(err, fmt.Sprintf("%s XMLRPC call failed", string(DStartedTime)))
+	if start {
+		return r.StartTorrent(ctx, t)
 	}
-	t.Created = time.Unix(int64(results.([]interface{})[0].(int)), 0)
-
-	return t, nil
+	return nil
 }
 
-// Delete removes the torrent
-func (r *Client) Delete(ctx context.Context, t Torrent) error {
-	_, err := r.xmlrpcClient.Call(ctx, "d.erase", t.Hash)
-	if err != nil {
-		return errors.Wrap(err, "d.erase XMLRPC call failed")
+// Ping checks that rTorrent is reachable and responding to XMLRPC calls
+func (r *Client) Ping(ctx context.Context) error {
+	// system.client_version is cheap and available on every rTorrent release
+	if _, err := r.xmlrpcClient.Call(ctx, "system.client_version"); err != nil {
+		return errors.Wrap(err, "system.client_version XMLRPC call failed")
 	}
 	return nil
 }
 
-// DeleteTied removes the torrent files
-func (r *Client) DeleteTied(ctx context.Context, t Torrent) error {
-	_, err := r.xmlrpcClient.Call(ctx, "d.delete_tied", t.Hash)
+// Versions contains the version information reported by rTorrent
+type Versions struct {
+	// ClientVersion is the rTorrent version
+	ClientVersion string
+	// LibraryVersion is the libtorrent version
+	LibraryVersion string
+	// APIVersion is the XMLRPC API version, empty on releases which do not report it
+	APIVersion string
+}
+
+// Versions returns the rTorrent, libtorrent and API versions in a single call.
+// Versions which rTorrent does not report are left empty.
+func (r *Client) Versions(ctx context.Context) (Versions, error) {
+	var v Versions
+	results, err := r.xmlrpcClient.MulticallBatch(ctx, []xmlrpc.Call{
+		{Method: "system.client_version"},
+		{Method: "system.library_version"},
+		{Method: "system.api_version"},
+	})
 	if err != nil {
-		return errors.Wrap(err, "d.delete_tied XMLRPC call failed")
+		return v, err
 	}
-	return nil
+	v.ClientVersion = versionString(results[0])
+	v.LibraryVersion = versionString(results[1])
+	v.APIVersion = versionString(results[2])
+	return v, nil
 }
 
-// SetForceDelete sets force delete flag
-func (r *Client) SetForceDelete(ctx context.Context, t Torrent, val bool) error {
-	var valStr string
-	if (val) {
-		valStr = "2"
-	} else {
-		valStr = "1"
+// versionString formats a version value, faults and unknown values become an empty string
+func versionString(v interface{}) string {
+	switch version := v.(type) {
+	case string:
+		return version
+	case int, int64:
+		return fmt.Sprintf("%d", version)
 	}
-	
-	_, err := r.xmlrpcClient.Call(ctx, "d.custom5.set", t.Hash, valStr)
-	if err != nil {
-		return errors.Wrap(err, "d.custom5.set (force delete) XMLRPC call failed")
+	return ""
+}
+
+// ProcessInfo contains information about the rTorrent process
+type ProcessInfo struct {
+	// PID is the process id of rTorrent
+	PID int
+	// Cwd is the working directory of rTorrent
+	Cwd string
+	// Hostname is the name of the rTorrent host
+	Hostname string
+	// Started is when rTorrent started, zero on releases which do not report it
+	Started time.Time
+}
+
+// Uptime returns how long rTorrent has been running, 0 if its start time is unknown
+func (p ProcessInfo) Uptime() time.Duration {
+	if p.Started.IsZero() {
+		return 0
 	}
-	return nil
+	return time.Since(p.Started)
 }
 
-// GetFiles returns all the files for a given `Torrent`
-func (r *Client) GetFiles(ctx context.Context, t Torrent) ([]File, error) {
-	args := []interface{}{t.Hash, 0, FPath.Query(), FSizeInBytes.Query()}
-	results, err := r.xmlrpcClient.Call(ctx, "f.multicall", args...)
-	var files []File
+// ProcessInfo returns information about the rTorrent process in a single call
+func (r *Client) ProcessInfo(ctx context.Context) (ProcessInfo, error) {
+	var p ProcessInfo
+	results, err := r.xmlrpcClient.MulticallBatch(ctx, []xmlrpc.Call{
+		{Method: "system.pid"},
+		{Method: "system.cwd"},
+		{Method: "system.hostname"},
+		{Method: "system.startup_time"},
+	})
 	if err != nil {
-		return files, errors.Wrap(err, "f.multicall XMLRPC call failed")
+		return p, err
 	}
-	for _, outerResult := range results.([]interface{}) {
-		for _, innerResult := range outerResult.([]interface{}) {
-			fileData := innerResult.([]interface{})
-			files = append(files, File{
-				Path: fileData[0].(string),
-				Size: fileData[1].(int),
-			})
+	for i, method := range []string{"system.pid", "system.cwd", "system.hostname"} {
+		if fault, ok := results[i].(xmlrpc.Fault); ok {
+			return p, errors.Wrap(fault, fmt.Sprintf("%s XMLRPC call failed", method))
 		}
 	}
-	return files, nil
+	if p.PID, err = asInt("system.pid", results[0]); err != nil {
+		return p, err
+	}
+	if p.Cwd, err = asString("system.cwd", results[1]); err != nil {
+		return p, err
+	}
+	if p.Hostname, err = asString("system.hostname", results[2]); err != nil {
+		return p, err
+	}
+	// system.startup_time is missing on older releases
+	if _, ok := results[3].(xmlrpc.Fault); !ok {
+		if p.Started, err = asTime("system.startup_time", results[3]); err != nil {
+			return p, err
+		}
+	}
+	return p, nil
 }
 
-// SetLabel sets the label on the given Torrent
-func (r *Client) SetLabel(ctx context.Context, t Torrent, newLabel string) error {
-	t.Label = newLabel
-	args := []interface{}{t.Hash, newLabel}
-	if _, err := r.xmlrpcClient.Call(ctx, "d.custom1.set", args...); err != nil {
-		return errors.Wrap(err, "d.custom1.set XMLRPC call failed")
+// ServerTime returns the current time of the rTorrent host, comparing it with time.Now
+// shows the clock skew between the client and the host
+func (r *Client) ServerTime(ctx context.Context) (time.Time, error) {
+	results, err := r.xmlrpcClient.Call(ctx, "system.time")
+	if err != nil {
+		return time.Time{}, errors.Wrap(err, "system.time XMLRPC call failed")
 	}
-	return nil
+	return resultTime("system.time", results)
 }
 
-// GetStatus returns the Status for a given Torrent
-func (r *Client) GetStatus(ctx context.Context, t Torrent) (Status, error) {
-	var s Status
-	// Completed
-	results, err := r.xmlrpcClient.Call(ctx, "d.complete", t.Hash)
-	if err != nil {
-		return s, errors.Wrap(err, "d.complete XMLRPC call failed")
+// SCGIInfo contains the network settings rTorrent exposes about how it is reachable.
+// The address of the SCGI socket, set with network.scgi.open_port or network.scgi.open_local,
+// can't be read back over XMLRPC, a successful SCGIInfo call is the proof it is listening.
+type SCGIInfo struct {
+	// DontRoute reports if the SCGI TCP socket is limited to directly connected hosts (network.scgi.dont_route)
+	DontRoute bool
+	// BindAddress is the address rTorrent binds its outgoing and listening sockets to, empty if unset (network.bind_address)
+	BindAddress string
+	// LocalAddress is the address rTorrent reports to trackers, empty if unset (network.local_address)
+	LocalAddress string
+}
+
+// SCGIInfo returns the network settings of rTorrent related to its SCGI socket in a single call
+func (r *Client) SCGIInfo(ctx context.Context) (SCGIInfo, error) {
+	var info SCGIInfo
+	methods := []string{"network.scgi.dont_route", "network.bind_address", "network.local_address"}
+	calls := make([]xmlrpc.Call, 0, len(methods))
+	for _, method := range methods {
+		calls = append(calls, xmlrpc.Call{Method: method})
 	}
-	s.Completed = results.([]interface{})[0].(int) > 0
-	// CompletedBytes
-	results, err = r.xmlrpcClient.Call(ctx, "d.completed_bytes", t.Hash)
+	results, err := r.xmlrpcClient.MulticallBatch(ctx, calls)
 	if err != nil {
-		return s, errors.Wrap(err, "d.completed_bytes XMLRPC call failed")
+		return info, err
 	}
-	s.CompletedBytes = results.([]interface{})[0].(int)
-	// DownRate
-	results, err = r.xmlrpcClient.Call(ctx, "d.down.rate", t.Hash)
-	if err != nil {
-		return s, errors.Wrap(err, "d.down.rate XMLRPC call failed")
+	for i, method := range methods {
+		if fault, ok := results[i].(xmlrpc.Fault); ok {
+			return info, errors.Wrap(fault, fmt.Sprintf("%s XMLRPC call failed", method))
+		}
 	}
-	s.DownRate = results.([]interface{})[0].(int)
-	// UpRate
-	results, err = r.xmlrpcClient.Call(ctx, "d.up.rate", t.Hash)
+	dontRoute, err := asInt("network.scgi.dont_route", results[0])
 	if err != nil {
-		return s, errors.Wrap(err, "d.up.rate XMLRPC call failed")
+		return info, err
 	}
-	s.UpRate = results.([]interface{})[0].(int)
-	// Ratio
-	results, err = r.xmlrpcClient.Call(ctx, "d.ratio", t.Hash)
-	if err != nil {
-		return s, errors.Wrap(err, "d.ratio XMLRPC call failed")
+	info.DontRoute = dontRoute == 1
+	if info.BindAddress, err = asString("network.bind_address", results[1]); err != nil {
+		return info, err
 	}
-	s.Ratio = float64(results.([]interface{})[0].(int)) / float64(1000)
-	// Size
-	results, err = r.xmlrpcClient.Call(ctx, "d.size_bytes", t.Hash)
-	if err != nil {
-		return s, errors.Wrap(err, "d.size_bytes XMLRPC call failed")
+	if info.LocalAddress, err = asString("network.local_address", results[2]); err != nil {
+		return info, err
 	}
-	s.Size = results.([]interface{})[0].(int)
-	return s, nil
+	return info, nil
 }
 
-// StartTorrent starts the torrent
-func (r *Client) StartTorrent(ctx context.Context, t Torrent) error {
-	_, err := r.xmlrpcClient.Call(ctx, "d.start", t.Hash)
+// CallString calls an arbitrary rTorrent command which returns a string
+func (r *Client) CallString(ctx context.Context, method string, args ...interface{}) (string, error) {
+	results, err := r.xmlrpcClient.Call(ctx, method, args...)
 	if err != nil {
-		return errors.Wrap(err, "d.start XMLRPC call failed")
+		return "", errors.Wrap(err, fmt.Sprintf("%s XMLRPC call failed", method))
 	}
-	return nil
+	return resultString(method, results)
 }
 
-// StopTorrent stops the torrent
-func (r *Client) StopTorrent(ctx context.Context, t Torrent) error {
-	_, err := r.xmlrpcClient.Call(ctx, "d.stop", t.Hash)
+// CallInt calls an arbitrary rTorrent command which returns an integer
+func (r *Client) CallInt(ctx context.Context, method string, args ...interface{}) (int64, error) {
+	results, err := r.xmlrpcClient.Call(ctx, method, args...)
 	if err != nil {
-		return errors.Wrap(err, "d.stop XMLRPC call failed")
+		return 0, errors.Wrap(err, fmt.Sprintf("%s XMLRPC call failed", method))
 	}
-	return nil
+	return resultInt64(method, results)
 }
 
-// CloseTorrent closes the torrent
-func (r *Client) CloseTorrent(ctx context.Context, t Torrent) error {
-	_, err := r.xmlrpcClient.Call(ctx, "d.close", t.Hash)
+// CallStringSlice calls an arbitrary rTorrent command which returns an array of strings, e.g. download_list
+func (r *Client) CallStringSlice(ctx context.Context, method string, args ...interface{}) ([]string, error) {
+	results, err := r.xmlrpcClient.Call(ctx, method, args...)
 	if err != nil {
-		return errors.Wrap(err, "d.close XMLRPC call failed")
+		return nil, errors.Wrap(err, fmt.Sprintf("%s XMLRPC call failed", method))
 	}
-	return nil
+	return resultStrings(method, results)
 }
 
-// OpenTorrent opens the torrent
-func (r *Client) OpenTorrent(ctx context.Context, t Torrent) error {
-	_, err := r.xmlrpcClient.Call(ctx, "d.open", t.Hash)
-	if err != nil {
-		return errors.Wrap(err, "d.open XMLRPC call failed")
-	}
-	return nil
+// IP returns the IP reported by this Client instance
+func (r *Client) IP(ctx context.Context) (string, error) {
+	return r.CallString(ctx, "network.bind_address")
 }
 
-// PauseTorrent pauses the torrent
-func (r *Client) PauseTorrent(ctx context.Context, t Torrent) error {
-	_, err := r.xmlrpcClient.Call(ctx, "d.pause", t.Hash)
+// Name returns the name reported by this Client instance
+func (r *Client) Name(ctx context.Context) (string, error) {
+	return r.CallString(ctx, "system.hostname")
+}
+
+// DownTotal returns the total downloaded metric reported by this Client instance (bytes)
+func (r *Client) DownTotal(ctx context.Context) (int64, error) {
+	return r.CallInt(ctx, "throttle.global_down.total")
+}
+
+// DownRate returns the current download rate reported by this Client instance (bytes/s)
+func (r *Client) DownRate(ctx context.Context) (int, error) {
+	rate, err := r.CallInt(ctx, "throttle.global_down.rate")
+	return int(rate), err
+}
+
+// UpTotal returns the total uploaded metric reported by this Client instance (bytes)
+func (r *Client) UpTotal(ctx context.Context) (int64, error) {
+	return r.CallInt(ctx, "throttle.global_up.total")
+}
+
+// UpRate returns the current upload rate reported by this Client instance (bytes/s)
+func (r *Client) UpRate(ctx context.Context) (int, error) {
+	rate, err := r.CallInt(ctx, "throttle.global_up.rate")
+	return int(rate), err
+}
+
+// FreeDiskSpace returns the space in bytes available on the filesystem holding dir on the rTorrent host.
+// rTorrent has no command for this, so it runs the POSIX "df -P -k -- dir" through execute.capture
+// and parses the available 1024-byte blocks.
+func (r *Client) FreeDiskSpace(ctx context.Context, dir string) (int64, error) {
+	out, err := r.CallString(ctx, "execute.capture", "", "df", "-P", "-k", "--", dir)
 	if err != nil {
-		return errors.Wrap(err, "d.pause XMLRPC call failed")
+		return 0, err
 	}
-	return nil
+	return parseDFAvailable(out)
 }
 
-// ResumeTorrent resumes the torrent
-func (r *Client) ResumeTorrent(ctx context.Context, t Torrent) error {
-	_, err := r.xmlrpcClient.Call(ctx, "d.resume", t.Hash)
+// parseDFAvailable returns the available bytes reported by "df -P -k" for a single path
+func parseDFAvailable(out string) (int64, error) {
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	if len(lines) < 2 {
+		return 0, errors.Errorf("unexpected df output %q", out)
+	}
+	// Filesystem 1024-blocks Used Available Capacity Mounted on
+	fields := strings.Fields(lines[len(lines)-1])
+	if len(fields) < 6 {
+		return 0, errors.Errorf("unexpected df output %q", out)
+	}
+	blocks, err := strconv.ParseInt(fields[3], 10, 64)
 	if err != nil {
-		return errors.Wrap(err, "d.resume XMLRPC call failed")
+		return 0, errors.Errorf("unexpected df output %q", out)
 	}
-	return nil
+	return blocks * 1024, nil
+}
+
+// GetTorrents returns all the torrents reported by this Client instance.
+// It returns ErrUnknownView if rTorrent doesn't define the view.
+func (r *Client) GetTorrents(ctx context.Context, view View) ([]Torrent, error) {
+	return r.viewTorrents(ctx, view, "d.multicall2", "", string(view))
+}
+
+// GetTorrentsFiltered returns the torrents of the view matching the filter expression,
+// the filtering is done by rTorrent (requires d.multicall.filtered, rTorrent 0.9.7+).
+//
+// Filters can be built with FilterLabelEquals or written by hand using rTorrent's syntax, e.g.
+//
+//	GetTorrentsFiltered(ctx, ViewMain, "d.complete=")
+func (r *Client) GetTorrentsFiltered(ctx context.Context, view View, filter string) ([]Torrent, error) {
+	return r.viewTorrents(ctx, view, "d.multicall.filtered", "", string(view), filter)
+}
+
+// IsValidView checks if rTorrent defines the view, see view.list
+func (r *Client) IsValidView(ctx context.Context, view View) (bool, error) {
+	views, err := r.CallStringSlice(ctx, "view.list")
+	if err != nil {
+		return false, err
+	}
+	for _, v := range views {
+		if v == string(view) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// viewTorrents fetches the torrents of the view like multicallTorrents, returning ErrUnknownView
+// if rTorrent doesn't define the view. Some rTorrent versions return no torrents for an unknown
+// view rather than a fault, so the view is checked with IsValidView when there are none.
+func (r *Client) viewTorrents(ctx context.Context, view View, method string, args ...interface{}) ([]Torrent, error) {
+	torrents, err := r.multicallTorrents(ctx, method, args...)
+	if isUnknownView(err) {
+		return torrents, errors.Wrap(ErrUnknownView, err.Error())
+	}
+	if err != nil || len(torrents) > 0 {
+		return torrents, err
+	}
+	valid, err := r.IsValidView(ctx, view)
+	if err != nil {
+		return torrents, err
+	}
+	if !valid {
+		return torrents, errors.Wrap(ErrUnknownView, string(view))
+	}
+	return torrents, nil
+}
+
+// GetTorrentsByLabel returns the torrents of the view with the given label. They are filtered
+// by rTorrent with GetTorrentsFiltered, or client-side on releases without d.multicall.filtered.
+func (r *Client) GetTorrentsByLabel(ctx context.Context, view View, label string) ([]Torrent, error) {
+	torrents, err := r.GetTorrentsFiltered(ctx, view, FilterLabelEquals(label))
+	if err == nil || !isUnknownMethod(err) {
+		return torrents, err
+	}
+	all, err := r.GetTorrents(ctx, view)
+	if err != nil {
+		return nil, err
+	}
+	torrents = []Torrent{}
+	for _, t := range all {
+		if t.Label == label {
+			torrents = append(torrents, t)
+		}
+	}
+	return torrents, nil
+}
+
+// GetCompletedTorrents returns the torrents which finished downloading, from ViewComplete.
+// If the view isn't defined on the instance, the torrents of ViewMain are filtered on Completed instead.
+func (r *Client) GetCompletedTorrents(ctx context.Context) ([]Torrent, error) {
+	return r.getTorrentsByCompletion(ctx, ViewComplete, true)
+}
+
+// GetIncompleteTorrents returns the torrents which are not finished, from ViewIncomplete.
+// If the view isn't defined on the instance, the torrents of ViewMain are filtered on Completed instead.
+func (r *Client) GetIncompleteTorrents(ctx context.Context) ([]Torrent, error) {
+	return r.getTorrentsByCompletion(ctx, ViewIncomplete, false)
+}
+
+// getTorrentsByCompletion returns the torrents of view, or of ViewMain with the given completion if view isn't defined
+func (r *Client) getTorrentsByCompletion(ctx context.Context, view View, completed bool) ([]Torrent, error) {
+	torrents, err := r.GetTorrents(ctx, view)
+	if err == nil || !isUnknownView(err) {
+		return torrents, err
+	}
+	all, err := r.GetTorrents(ctx, ViewMain)
+	if err != nil {
+		return nil, err
+	}
+	torrents = []Torrent{}
+	for _, t := range all {
+		if t.Completed == completed {
+			torrents = append(torrents, t)
+		}
+	}
+	return torrents, nil
+}
+
+// GetTorrentsSorted returns the torrents of the view ordered by the given field.
+//
+// rTorrent can only sort views through their global configuration, which would affect
+// every other client, so the torrents are sorted client-side. The sort is stable: torrents
+// with equal values, such as unset (zero) timestamps, keep the order reported by rTorrent.
+// Supported fields are those populated on Torrent, other fields return an error.
+func (r *Client) GetTorrentsSorted(ctx context.Context, view View, sortField Field, ascending bool) ([]Torrent, error) {
+	less, ok := torrentLess[sortField]
+	if !ok {
+		return nil, errors.Errorf("cannot sort torrents by %s", sortField)
+	}
+	torrents, err := r.GetTorrents(ctx, view)
+	if err != nil {
+		return torrents, err
+	}
+	sort.SliceStable(torrents, func(i, j int) bool {
+		if ascending {
+			return less(torrents[i], torrents[j])
+		}
+		return less(torrents[j], torrents[i])
+	})
+	return torrents, nil
+}
+
+// torrentLess holds the ordering of the fields which torrents can be sorted by
+var torrentLess = map[Field]func(a, b Torrent) bool{
+	DName:           func(a, b Torrent) bool { return a.Name < b.Name },
+	DSizeInBytes:    func(a, b Torrent) bool { return a.Size < b.Size },
+	DHash:           func(a, b Torrent) bool { return a.Hash < b.Hash },
+	DLabel:          func(a, b Torrent) bool { return a.Label < b.Label },
+	DDirectory:      func(a, b Torrent) bool { return a.Path < b.Path },
+	DComplete:       func(a, b Torrent) bool { return !a.Completed && b.Completed },
+	DRatio:          func(a, b Torrent) bool { return a.Ratio < b.Ratio },
+	DCreationTime:   func(a, b Torrent) bool { return a.Created.Before(b.Created) },
+	DAddedTime:      func(a, b Torrent) bool { return a.Added.Before(b.Added) },
+	DStateChanged:   func(a, b Torrent) bool { return a.StateChanged.Before(b.StateChanged) },
+	DFinishedTime:   func(a, b Torrent) bool { return a.Finished.Before(b.Finished) },
+	DStartedTime:    func(a, b Torrent) bool { return a.Started.Before(b.Started) },
+	DPriority:       func(a, b Torrent) bool { return a.Priority < b.Priority },
+	DPeersConnected: func(a, b Torrent) bool { return a.PeersConnected < b.PeersConnected },
+	DPeersComplete:  func(a, b Torrent) bool { return a.Seeders < b.Seeders },
+	DPeersAccounted: func(a, b Torrent) bool { return a.Leechers < b.Leechers },
+	DUpTotal:        func(a, b Torrent) bool { return a.UpTotal < b.UpTotal },
+	DDownTotal:      func(a, b Torrent) bool { return a.DownTotal < b.DownTotal },
+}
+
+// FilterLabelEquals returns a filter expression matching torrents with the given label
+func FilterLabelEquals(label string) string {
+	return fmt.Sprintf("equal={%s,cat=%s}", DLabel.Query(), quoteArg(label))
+}
+
+// quoteArg quotes a string so it is taken literally inside an rTorrent command
+func quoteArg(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
+
+// multicallTorrents fetches torrentFields with the given multicall command and decodes the torrents
+func (r *Client) multicallTorrents(ctx context.Context, method string, args ...interface{}) ([]Torrent, error) {
+	for _, field := range torrentFields {
+		args = append(args, field.Query())
+	}
+	results, err := r.xmlrpcClient.Call(ctx, method, args...)
+	torrents := []Torrent{}
+	if err != nil {
+		return torrents, errors.Wrap(err, fmt.Sprintf("%s XMLRPC call failed", method))
+	}
+	for _, outerResult := range asRows(results) {
+		for _, innerResult := range asRows(outerResult) {
+			torrent, err := decodeTorrent(innerResult)
+			if err != nil {
+				return torrents, errors.Wrap(err, fmt.Sprintf("%s XMLRPC call returned unexpected data", method))
+			}
+			torrents = append(torrents, torrent)
+		}
+	}
+	return torrents, nil
+}
+
+// Watch polls the torrents of the view every interval and sends a snapshot on the returned
+// channel whenever they changed, starting with the current torrents. The channel is closed
+// once ctx is done. An error is only returned if interval is not positive or the initial poll
+// fails, failures of later polls are logged and the snapshot is skipped.
+//
+// Watch is unrelated to the watch directories of rTorrent, the schedule2 entries of its
+// configuration loading .torrent files from a directory. rTorrent has no command listing its
+// schedules or their commands, so they can't be read over XMLRPC: check a watch directory is
+// configured on the rTorrent host itself, e.g. in its rtorrent.rc.
+func (r *Client) Watch(ctx context.Context, view View, interval time.Duration) (<-chan []Torrent, error) {
+	if interval <= 0 {
+		return nil, errors.Errorf("invalid watch interval %s", interval)
+	}
+	torrents, err := r.GetTorrents(ctx, view)
+	if err != nil {
+		return nil, err
+	}
+
+	ch := make(chan []Torrent)
+	go func() {
+		defer close(ch)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		last := torrents
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case ch <- torrents:
+			}
+			for changed := false; !changed; {
+				select {
+				case <-ctx.Done():
+					return
+				case <-ticker.C:
+				}
+				current, err := r.GetTorrents(ctx, view)
+				if err != nil {
+					r.logError(ctx, "watch", err, slog.String("view", string(view)))
+					continue
+				}
+				changed = torrentsChanged(last, current)
+				torrents, last = current, current
+			}
+		}
+	}()
+	return ch, nil
+}
+
+// torrentsChanged reports whether any torrent was added, removed or modified, regardless of order
+func torrentsChanged(before, after []Torrent) bool {
+	if len(before) != len(after) {
+		return true
+	}
+	byHash := make(map[string]Torrent, len(before))
+	for _, t := range before {
+		byHash[t.Hash] = t
+	}
+	for _, t := range after {
+		prev, ok := byHash[t.Hash]
+		if !ok || prev != t {
+			return true
+		}
+	}
+	return false
+}
+
+// GetTorrentsPage returns at most limit torrents of the view, skipping the first offset torrents.
+//
+// rTorrent cannot page natively, so the hashes of the view are listed first and the
+// torrents of the requested window are fetched in a second call, field by field. When the
+// window covers most of the view all its torrents are fetched with d.multicall2 instead,
+// which is cheaper. The view may change between both calls: torrents removed in the
+// meantime are left out of the page.
+func (r *Client) GetTorrentsPage(ctx context.Context, view View, offset, limit int) ([]Torrent, error) {
+	var torrents []Torrent
+	if offset < 0 || limit < 0 {
+		return torrents, errors.Errorf("invalid page offset %d, limit %d", offset, limit)
+	}
+	results, err := r.xmlrpcClient.Call(ctx, "download_list", "", string(view))
+	if err != nil {
+		return torrents, errors.Wrap(err, "download_list XMLRPC call failed")
+	}
+	list, err := firstResult("download_list", results)
+	if err != nil {
+		return torrents, err
+	}
+	all, err := asSlice("download_list", list)
+	if err != nil {
+		return torrents, err
+	}
+	if offset >= len(all) || limit == 0 {
+		return torrents, nil
+	}
+	window := all[offset:]
+	if limit < len(window) {
+		window = window[:limit]
+	}
+	hashes := make([]string, 0, len(window))
+	for _, v := range window {
+		hash, err := asString("download_list", v)
+		if err != nil {
+			return torrents, err
+		}
+		hashes = append(hashes, hash)
+	}
+
+	if 2*len(hashes) >= len(all) {
+		return r.pageOfView(ctx, view, hashes)
+	}
+	calls := make([]xmlrpc.Call, 0, len(hashes)*len(torrentFields))
+	for _, hash := range hashes {
+		for _, field := range torrentFields {
+			calls = append(calls, field.call(hash))
+		}
+	}
+	values, err := r.xmlrpcClient.MulticallBatch(ctx, calls)
+	if err != nil {
+		return torrents, err
+	}
+	for i := 0; i < len(values); i += len(torrentFields) {
+		torrentData := values[i : i+len(torrentFields)]
+		removed, err := torrentFault(torrentData)
+		if err != nil {
+			return torrents, err
+		}
+		if removed {
+			// removed since the hashes were listed
+			continue
+		}
+		torrent, err := decodeTorrent(torrentData)
+		if err != nil {
+			return torrents, errors.Wrap(err, "system.multicall XMLRPC call returned unexpected data")
+		}
+		torrents = append(torrents, torrent)
+	}
+	return torrents, nil
+}
+
+// pageOfView fetches all the torrents of the view and returns those with the hashes, in their order
+func (r *Client) pageOfView(ctx context.Context, view View, hashes []string) ([]Torrent, error) {
+	all, err := r.GetTorrents(ctx, view)
+	if err != nil {
+		return nil, err
+	}
+	byHash := make(map[string]Torrent, len(all))
+	for _, t := range all {
+		byHash[t.Hash] = t
+	}
+	var torrents []Torrent
+	for _, hash := range hashes {
+		if t, ok := byHash[hash]; ok {
+			torrents = append(torrents, t)
+		}
+	}
+	return torrents, nil
+}
+
+// torrentFault checks the values of torrentFields fetched for a torrent for faults. It reports
+// whether the torrent is no longer loaded, and returns any other fault as an error.
+func torrentFault(values []interface{}) (removed bool, err error) {
+	for i, v := range values {
+		fault, ok := v.(xmlrpc.Fault)
+		if !ok {
+			continue
+		}
+		if !isNotFound(fault) {
+			return false, errors.Wrap(fault, fmt.Sprintf("%s XMLRPC call failed", torrentFields[i]))
+		}
+		removed = true
+	}
+	return removed, nil
+}
+
+// ViewSize returns the number of torrents in the view, without fetching them
+func (r *Client) ViewSize(ctx context.Context, view View) (int, error) {
+	results, err := r.xmlrpcClient.Call(ctx, "view.size", "", string(view))
+	if err != nil {
+		return 0, errors.Wrap(err, "view.size XMLRPC call failed")
+	}
+	return resultInt("view.size", results)
+}
+
+// TorrentCount returns the number of torrents loaded in rTorrent, the size of ViewMain.
+// Prefer it to counting the result of GetTorrents, which fetches every torrent.
+func (r *Client) TorrentCount(ctx context.Context) (int, error) {
+	return r.ViewSize(ctx, ViewMain)
+}
+
+// ViewSizes returns the number of torrents in each of the views in a single system.multicall
+func (r *Client) ViewSizes(ctx context.Context, views ...View) (map[View]int, error) {
+	calls := make([]xmlrpc.Call, 0, len(views))
+	for _, view := range views {
+		calls = append(calls, xmlrpc.Call{Method: "view.size", Params: []interface{}{"", string(view)}})
+	}
+	results, err := r.xmlrpcClient.MulticallBatch(ctx, calls)
+	if err != nil {
+		return nil, err
+	}
+	sizes := make(map[View]int, len(views))
+	for i, result := range results {
+		if fault, ok := result.(xmlrpc.Fault); ok {
+			return nil, errors.Wrap(fault, fmt.Sprintf("view.size XMLRPC call failed for %s", views[i]))
+		}
+		if sizes[views[i]], err = asInt("view.size", result); err != nil {
+			return nil, err
+		}
+	}
+	return sizes, nil
+}
+
+// torrentColumns are the fields fetched for a Torrent, from the rtorrent tags of its struct fields
+var torrentColumns = structColumns(reflect.TypeOf(Torrent{}))
+
+// torrentFields are the fields of torrentColumns, in the order they are fetched
+var torrentFields = columnFields(torrentColumns)
+
+// decodeTorrent decodes the values of torrentFields for a single torrent
+func decodeTorrent(v interface{}) (Torrent, error) {
+	var t Torrent
+	err := decodeColumns("torrent", v, torrentColumns, reflect.ValueOf(&t).Elem())
+	if t.Added.IsZero() {
+		// no add time was recorded, the first start is the closest rTorrent keeps
+		t.Added = t.Started
+	}
+	return t, err
+}
+
+// GetTorrent returns the torrent identified by the given hash
+func (r *Client) GetTorrent(ctx context.Context, hash string) (Torrent, error) {
+	values, err := r.fetchFields(ctx, hash, torrentFields)
+	if err != nil {
+		return Torrent{Hash: hash}, err
+	}
+	t, err := decodeTorrent(values)
+	if err != nil {
+		return t, errors.Wrap(err, "system.multicall XMLRPC call returned unexpected data")
+	}
+	return t, nil
+}
+
+// DeleteOptions controls what is removed along with a torrent, see DeleteWithOptions
+type DeleteOptions struct {
+	// RemoveTiedFile deletes the .torrent file the torrent is tied to, see DeleteTied
+	RemoveTiedFile bool
+	// KeepTiedFile unties the torrent first, so its .torrent file is kept even if rTorrent is
+	// configured to delete tied files on erase, see Untie. It contradicts RemoveTiedFile.
+	// With neither set rTorrent's own configuration decides.
+	KeepTiedFile bool
+	// RemoveData deletes the downloaded data from the rTorrent host, see DeleteWithData
+	RemoveData bool
+	// IgnoreMissing treats a torrent rTorrent doesn't know as deleted, so retrying a delete which
+	// timed out after rTorrent erased the torrent succeeds. With RemoveData the data of a missing
+	// torrent is left untouched, as its files can no longer be listed.
+	IgnoreMissing bool
+}
+
+// Delete removes the torrent from rTorrent with d.erase, its data is left on disk
+func (r *Client) Delete(ctx context.Context, t Torrent) error {
+	_, err := r.callHash(ctx, "d.erase", t.Hash)
+	if err != nil {
+		return errors.Wrap(err, "d.erase XMLRPC call failed")
+	}
+	return nil
+}
+
+// DeleteWithData removes the torrent and deletes its downloaded data from the rTorrent host.
+//
+// The torrent is closed and each of its files is removed with "rm -f" executed by rTorrent:
+// d.base_path joined with every f.path of a multi-file torrent, or d.base_path itself for a
+// single file. The directories below d.base_path are then removed if empty, and d.base_path
+// too if it is named after the torrent, so a shared download directory is never removed.
+// The torrent is only erased once all its files are gone, otherwise the error lists the
+// files left behind and the torrent stays loaded, closed, so the delete can be retried.
+// The tied .torrent file (see DeleteTied) and the session files are left untouched.
+func (r *Client) DeleteWithData(ctx context.Context, t Torrent) error {
+	return r.DeleteWithOptions(ctx, t, DeleteOptions{RemoveData: true})
+}
+
+// DeleteWithOptions erases the torrent from rTorrent, removing its tied .torrent file
+// and its data as requested by opts
+func (r *Client) DeleteWithOptions(ctx context.Context, t Torrent, opts DeleteOptions) error {
+	if opts.RemoveTiedFile && opts.KeepTiedFile {
+		return errors.New("delete options RemoveTiedFile and KeepTiedFile contradict each other")
+	}
+	err := r.deleteTorrent(ctx, t, opts)
+	if opts.IgnoreMissing && (errors.Is(err, ErrTorrentNotFound) || isNotFound(err)) {
+		return nil
+	}
+	return err
+}
+
+// deleteTorrent erases the torrent as requested by opts, see DeleteWithOptions
+func (r *Client) deleteTorrent(ctx context.Context, t Torrent, opts DeleteOptions) error {
+	var data torrentData
+	if opts.RemoveData {
+		var err error
+		if data, err = r.dataFiles(ctx, t); err != nil {
+			return err
+		}
+	}
+
+	if opts.RemoveTiedFile {
+		if err := r.DeleteTied(ctx, t); err != nil {
+			return err
+		}
+	} else if opts.KeepTiedFile {
+		if err := r.Untie(ctx, t); err != nil {
+			return err
+		}
+	}
+
+	if opts.RemoveData {
+		if _, err := r.callHash(ctx, "d.close", t.Hash); err != nil {
+			return errors.Wrap(err, "d.close XMLRPC call failed")
+		}
+		if err := r.removeData(ctx, t, data); err != nil {
+			return err
+		}
+	}
+	return r.Delete(ctx, t)
+}
+
+// torrentData are the paths of the downloaded data of a torrent on the rTorrent host, see dataFiles
+type torrentData struct {
+	// files are the files of the torrent
+	files []string
+	// dirs are the directories to remove once empty, the deepest first
+	dirs []string
+}
+
+// dataFiles returns the paths of the files of the torrent, and of the directories holding them
+// which belong to the torrent
+func (r *Client) dataFiles(ctx context.Context, t Torrent) (torrentData, error) {
+	var data torrentData
+	values, err := r.fetchFields(ctx, t.Hash, []Field{DBasePath, DDirectory, DName, DIsMultiFile})
+	if err != nil {
+		return data, err
+	}
+	basePath, err := asString(DBasePath.Cmd(), values[0])
+	if err != nil {
+		return data, err
+	}
+	dir, err := asString(DDirectory.Cmd(), values[1])
+	if err != nil {
+		return data, err
+	}
+	name, err := asString(DName.Cmd(), values[2])
+	if err != nil {
+		return data, err
+	}
+	multiFile, err := asInt(DIsMultiFile.Cmd(), values[3])
+	if err != nil {
+		return data, err
+	}
+	if basePath == "" {
+		// d.base_path is unset while the torrent is closed
+		basePath = dir
+		if multiFile == 0 {
+			basePath = path.Join(dir, name)
+		}
+	}
+	if basePath = path.Clean(basePath); !path.IsAbs(basePath) || basePath == "/" {
+		return data, errors.Errorf("refusing to delete data of %s at %q", t.Hash, basePath)
+	}
+	if multiFile == 0 {
+		data.files = []string{basePath}
+		return data, nil
+	}
+
+	files, err := r.GetFiles(ctx, t)
+	if err != nil {
+		return data, err
+	}
+	dirs := map[string]bool{}
+	for _, f := range files {
+		elems := strings.Split(f.Path, "/")
+		for _, elem := range elems {
+			if !validPathElem(elem) {
+				return data, errors.Errorf("refusing to delete data of %s: invalid file path %q", t.Hash, f.Path)
+			}
+		}
+		data.files = append(data.files, path.Join(basePath, f.Path))
+		for i := 1; i < len(elems); i++ {
+			dirs[path.Join(basePath, path.Join(elems[:i]...))] = true
+		}
+	}
+	if path.Base(basePath) == name {
+		dirs[basePath] = true
+	}
+	for d := range dirs {
+		data.dirs = append(data.dirs, d)
+	}
+	// a directory sorts after its parent, so removing in reverse order empties children first
+	sort.Sort(sort.Reverse(sort.StringSlice(data.dirs)))
+	return data, nil
+}
+
+// removeData deletes the files of the torrent, then its directories which are left empty.
+// The files left behind are reported in the returned error.
+func (r *Client) removeData(ctx context.Context, t Torrent, data torrentData) error {
+	calls := make([]xmlrpc.Call, 0, len(data.files))
+	for _, file := range data.files {
+		calls = append(calls, xmlrpc.Call{Method: "execute.throw", Params: []interface{}{"", "rm", "-f", "--", file}})
+	}
+	values, err := r.xmlrpcClient.MulticallBatch(ctx, calls)
+	if err != nil {
+		return errors.Wrap(err, "execute.throw (rm) XMLRPC call failed")
+	}
+	var left []string
+	for i, v := range values {
+		if _, ok := v.(xmlrpc.Fault); ok {
+			left = append(left, data.files[i])
+		}
+	}
+	if len(left) > 0 {
+		return errors.Errorf("could not delete %d files of %s: %s", len(left), t.Hash, strings.Join(left, ", "))
+	}
+	if len(data.dirs) == 0 {
+		return nil
+	}
+	// rmdir refuses directories which are not empty, e.g. holding files which are not the torrent's
+	args := append([]interface{}{"", "rmdir", "--"}, stringArgs(data.dirs)...)
+	if _, err := r.xmlrpcClient.Call(ctx, "execute.nothrow", args...); err != nil {
+		return errors.Wrap(err, "execute.nothrow (rmdir) XMLRPC call failed")
+	}
+	return nil
+}
+
+// stringArgs converts strings to XMLRPC call arguments
+func stringArgs(values []string) []interface{} {
+	args := make([]interface{}, 0, len(values))
+	for _, v := range values {
+		args = append(args, v)
+	}
+	return args
+}
+
+// DeleteMany removes the torrents in a single system.multicall, see Delete.
+// Torrents which could not be removed are reported in the returned error.
+func (r *Client) DeleteMany(ctx context.Context, torrents []Torrent) error {
+	return r.callEach(ctx, "d.erase", hashesOf(torrents))
+}
+
+// callEach calls the method for each hash, followed by args, in a single system.multicall.
+// Every call is made even if some fail, the failed hashes are reported in the returned error.
+func (r *Client) callEach(ctx context.Context, method string, hashes []string, args ...interface{}) error {
+	if len(hashes) == 0 {
+		return nil
+	}
+	calls := make([]xmlrpc.Call, 0, len(hashes))
+	for _, hash := range hashes {
+		hash, err := NormalizeHash(hash)
+		if err != nil {
+			return err
+		}
+		calls = append(calls, xmlrpc.Call{Method: method, Params: append([]interface{}{hash}, args...)})
+	}
+	values, err := r.xmlrpcClient.MulticallBatch(ctx, calls)
+	if err != nil {
+		return err
+	}
+	var failed []string
+	for i, v := range values {
+		if fault, ok := v.(xmlrpc.Fault); ok {
+			failed = append(failed, fmt.Sprintf("%s: %v", hashes[i], fault))
+		}
+	}
+	if len(failed) > 0 {
+		return errors.Errorf("%s XMLRPC call failed for %d torrents: %s", method, len(failed), strings.Join(failed, "; "))
+	}
+	return nil
+}
+
+// callHash calls the method on the torrent identified by hash, followed by args.
+// The hash is normalized first, see NormalizeHash.
+func (r *Client) callHash(ctx context.Context, method, hash string, args ...interface{}) (interface{}, error) {
+	hash, err := NormalizeHash(hash)
+	if err != nil {
+		return nil, err
+	}
+	return r.xmlrpcClient.Call(ctx, method, append([]interface{}{hash}, args...)...)
+}
+
+// hashesOf returns the hashes of the torrents
+func hashesOf(torrents []Torrent) []string {
+	hashes := make([]string, 0, len(torrents))
+	for _, t := range torrents {
+		hashes = append(hashes, t.Hash)
+	}
+	return hashes
+}
+
+// DeleteTied removes the torrent files
+func (r *Client) DeleteTied(ctx context.Context, t Torrent) error {
+	_, err := r.callHash(ctx, "d.delete_tied", t.Hash)
+	if err != nil {
+		return errors.Wrap(err, "d.delete_tied XMLRPC call failed")
+	}
+	return nil
+}
+
+// TiedFile returns the path of the .torrent file the torrent is tied to, empty if it is not tied to one
+func (r *Client) TiedFile(ctx context.Context, t Torrent) (string, error) {
+	results, err := r.callHash(ctx, DTiedToFile.Cmd(), t.Hash)
+	if err != nil {
+		return "", errors.Wrap(err, "d.tied_to_file XMLRPC call failed")
+	}
+	return resultString(DTiedToFile.Cmd(), results)
+}
+
+// Untie unties the torrent from its .torrent file, so that DeleteTied and erasing the torrent keep the file
+func (r *Client) Untie(ctx context.Context, t Torrent) error {
+	_, err := r.callHash(ctx, "d.tied_to_file.set", t.Hash, "")
+	if err != nil {
+		return errors.Wrap(err, "d.tied_to_file.set XMLRPC call failed")
+	}
+	return nil
+}
+
+// SetForceDelete sets force delete flag
+func (r *Client) SetForceDelete(ctx context.Context, t Torrent, val bool) error {
+	var valStr string
+	if (val) {
+		valStr = "2"
+	} else {
+		valStr = "1"
+	}
+	
+	_, err := r.callHash(ctx, "d.custom5.set", t.Hash, valStr)
+	if err != nil {
+		return errors.Wrap(err, "d.custom5.set (force delete) XMLRPC call failed")
+	}
+	return nil
+}
+
+// GetFiles returns all the files for a given `Torrent`
+func (r *Client) GetFiles(ctx context.Context, t Torrent) ([]File, error) {
+	args := []interface{}{0}
+	for _, field := range fileFields {
+		args = append(args, field.Query())
+	}
+	results, err := r.callHash(ctx, "f.multicall", t.Hash, args...)
+	files := []File{}
+	if err != nil {
+		if isNotFound(err) {
+			return files, errors.Wrap(ErrTorrentNotFound, t.Hash)
+		}
+		return files, errors.Wrap(err, "f.multicall XMLRPC call failed")
+	}
+	for _, outerResult := range asRows(results) {
+		innerFiles, err := decodeFiles(outerResult)
+		if err != nil {
+			return files, errors.Wrap(err, "f.multicall XMLRPC call returned unexpected data")
+		}
+		files = append(files, innerFiles...)
+	}
+	return files, nil
+}
+
+// GetFilesMany returns the files of each of the torrents in a single system.multicall, keyed by hash.
+// Torrents which are no longer loaded are left out of the map.
+//
+// The file lists of all the torrents are decoded from a single response held in memory,
+// so batch torrents with many thousands of files in smaller groups.
+func (r *Client) GetFilesMany(ctx context.Context, torrents []Torrent) (map[string][]File, error) {
+	hashes := make([]string, 0, len(torrents))
+	calls := make([]xmlrpc.Call, 0, len(torrents))
+	for _, t := range torrents {
+		hash, err := NormalizeHash(t.Hash)
+		if err != nil {
+			return nil, err
+		}
+		params := []interface{}{hash, 0}
+		for _, field := range fileFields {
+			params = append(params, field.Query())
+		}
+		hashes = append(hashes, hash)
+		calls = append(calls, xmlrpc.Call{Method: "f.multicall", Params: params})
+	}
+	files := make(map[string][]File, len(torrents))
+	if len(calls) == 0 {
+		return files, nil
+	}
+
+	results, err := r.xmlrpcClient.MulticallBatch(ctx, calls)
+	if err != nil {
+		return nil, err
+	}
+	for i, result := range results {
+		if fault, ok := result.(xmlrpc.Fault); ok {
+			if isNotFound(fault) {
+				continue
+			}
+			return nil, errors.Wrap(fault, fmt.Sprintf("f.multicall XMLRPC call failed for %s", hashes[i]))
+		}
+		torrentFiles, err := decodeFiles(result)
+		if err != nil {
+			return nil, errors.Wrap(err, "f.multicall XMLRPC call returned unexpected data")
+		}
+		files[hashes[i]] = torrentFiles
+	}
+	return files, nil
+}
+
+// decodeFiles decodes the rows of an f.multicall call
+func decodeFiles(v interface{}) ([]File, error) {
+	rows := asRows(v)
+	files := make([]File, 0, len(rows))
+	for _, row := range rows {
+		file, err := decodeFile(row)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, file)
+	}
+	return files, nil
+}
+
+// FilePath returns the absolute path on the rTorrent host of the file at fileIndex in the torrent,
+// in the order returned by GetFiles. It is d.base_path joined with f.path for a multi-file torrent,
+// and d.base_path itself for a single-file torrent. d.directory is used while d.base_path is unset,
+// e.g. for a closed torrent.
+func (r *Client) FilePath(ctx context.Context, t Torrent, fileIndex int) (string, error) {
+	if fileIndex < 0 {
+		return "", errors.Errorf("invalid file index %d", fileIndex)
+	}
+	values, err := r.fetchFields(ctx, t.Hash, []Field{DBasePath, DDirectory, DName, DIsMultiFile})
+	if err != nil {
+		return "", err
+	}
+	basePath, err := asString(DBasePath.Cmd(), values[0])
+	if err != nil {
+		return "", err
+	}
+	dir, err := asString(DDirectory.Cmd(), values[1])
+	if err != nil {
+		return "", err
+	}
+	name, err := asString(DName.Cmd(), values[2])
+	if err != nil {
+		return "", err
+	}
+	multiFile, err := asInt(DIsMultiFile.Cmd(), values[3])
+	if err != nil {
+		return "", err
+	}
+
+	if multiFile == 0 {
+		if fileIndex != 0 {
+			return "", errors.Errorf("invalid file index %d for single-file torrent", fileIndex)
+		}
+		if basePath == "" {
+			basePath = path.Join(dir, name)
+		}
+		return basePath, nil
+	}
+
+	if basePath == "" {
+		// d.directory of a multi-file torrent is its base path
+		basePath = dir
+	}
+	hash, err := NormalizeHash(t.Hash)
+	if err != nil {
+		return "", err
+	}
+	results, err := r.xmlrpcClient.Call(ctx, FPath.Cmd(), fmt.Sprintf("%s:f%d", hash, fileIndex))
+	if err != nil {
+		return "", errors.Wrap(err, "f.path XMLRPC call failed")
+	}
+	filePath, err := resultString(FPath.Cmd(), results)
+	if err != nil {
+		return "", err
+	}
+	return path.Join(basePath, filePath), nil
+}
+
+// fileFields are the fields fetched for every File, in the order expected by decodeFile
+var fileFields = []Field{FPath, FSizeInBytes, FOffset, FCompletedChunks, FSizeChunks, FRangeFirst, FRangeSecond}
+
+// decodeFile decodes a single row of the f.multicall call made by GetFiles
+func decodeFile(v interface{}) (File, error) {
+	var f File
+	fileData, err := asRow("file", v, len(fileFields))
+	if err != nil {
+		return f, err
+	}
+	if f.Path, err = asString(FPath.Cmd(), fileData[0]); err != nil {
+		return f, err
+	}
+	if f.Size, err = asInt64(FSizeInBytes.Cmd(), fileData[1]); err != nil {
+		return f, err
+	}
+	if f.Offset, err = asInt64(FOffset.Cmd(), fileData[2]); err != nil {
+		return f, err
+	}
+	if f.CompletedChunks, err = asInt64(FCompletedChunks.Cmd(), fileData[3]); err != nil {
+		return f, err
+	}
+	if f.TotalChunks, err = asInt64(FSizeChunks.Cmd(), fileData[4]); err != nil {
+		return f, err
+	}
+	if f.RangeFirst, err = asInt64(FRangeFirst.Cmd(), fileData[5]); err != nil {
+		return f, err
+	}
+	if f.RangeSecond, err = asInt64(FRangeSecond.Cmd(), fileData[6]); err != nil {
+		return f, err
+	}
+	return f, nil
+}
+
+// ChunksSeen returns, for each chunk of the torrent, the number of connected peers which have it,
+// as reported by d.chunks_seen. rTorrent caps the counts at 255, and reports no chunks while the torrent is closed.
+func (r *Client) ChunksSeen(ctx context.Context, t Torrent) ([]int, error) {
+	results, err := r.callHash(ctx, "d.chunks_seen", t.Hash)
+	if err != nil {
+		return nil, errors.Wrap(err, "d.chunks_seen XMLRPC call failed")
+	}
+	seen, err := resultString("d.chunks_seen", results)
+	if err != nil {
+		return nil, err
+	}
+	counts, err := hex.DecodeString(seen)
+	if err != nil {
+		return nil, errors.Wrap(err, "d.chunks_seen XMLRPC call returned unexpected data")
+	}
+	chunks := make([]int, len(counts))
+	for i, n := range counts {
+		chunks[i] = int(n)
+	}
+	return chunks, nil
+}
+
+// Availability returns the distributed copies of the torrent among its connected peers: the integer part is
+// the number of full copies, the fraction is the share of chunks seen more often than that. Below 1.0 no full
+// copy is reachable from the peers. It is computed from ChunksSeen, 0 while the torrent is closed.
+func (r *Client) Availability(ctx context.Context, t Torrent) (float64, error) {
+	chunks, err := r.ChunksSeen(ctx, t)
+	if err != nil {
+		return 0, err
+	}
+	return availability(chunks), nil
+}
+
+// availability returns the distributed copies for the given chunk counts
+func availability(chunks []int) float64 {
+	if len(chunks) == 0 {
+		return 0
+	}
+	least := chunks[0]
+	for _, n := range chunks {
+		least = min(least, n)
+	}
+	above := 0
+	for _, n := range chunks {
+		if n > least {
+			above++
+		}
+	}
+	return float64(least) + float64(above)/float64(len(chunks))
+}
+
+// trackerColumns are the fields fetched for a Tracker, from the rtorrent tags of its struct fields
+var trackerColumns = structColumns(reflect.TypeOf(Tracker{}))
+
+// GetTrackers returns the trackers of the torrent, in the order rTorrent announces to them
+func (r *Client) GetTrackers(ctx context.Context, t Torrent) ([]Tracker, error) {
+	args := []interface{}{""}
+	for _, field := range columnFields(trackerColumns) {
+		args = append(args, field.Query())
+	}
+	results, err := r.callHash(ctx, "t.multicall", t.Hash, args...)
+	trackers := []Tracker{}
+	if err != nil {
+		if isNotFound(err) {
+			return trackers, errors.Wrap(ErrTorrentNotFound, t.Hash)
+		}
+		return trackers, errors.Wrap(err, "t.multicall XMLRPC call failed")
+	}
+	rows, err := firstResult("t.multicall", results)
+	if err != nil {
+		return trackers, errors.Wrap(err, "t.multicall XMLRPC call returned unexpected data")
+	}
+	for _, row := range asRows(rows) {
+		var tracker Tracker
+		if err := decodeColumns("tracker", row, trackerColumns, reflect.ValueOf(&tracker).Elem()); err != nil {
+			return trackers, errors.Wrap(err, "t.multicall XMLRPC call returned unexpected data")
+		}
+		trackers = append(trackers, tracker)
+	}
+	return trackers, nil
+}
+
+// ScrapeTotals returns the seeders and leechers of the torrent summed over all its trackers.
+// Trackers which have not been scraped yet, or report negative counts, are left out of the totals.
+func (r *Client) ScrapeTotals(ctx context.Context, t Torrent) (seeders, leechers int, err error) {
+	results, err := r.callHash(ctx, "t.multicall", t.Hash, "", TScrapeTimeLast.Query(), TScrapeComplete.Query(), TScrapeIncomplete.Query())
+	if err != nil {
+		if isNotFound(err) {
+			return 0, 0, errors.Wrap(ErrTorrentNotFound, t.Hash)
+		}
+		return 0, 0, errors.Wrap(err, "t.multicall XMLRPC call failed")
+	}
+	rows, err := firstResult("t.multicall", results)
+	if err != nil {
+		return 0, 0, errors.Wrap(err, "t.multicall XMLRPC call returned unexpected data")
+	}
+	trackers, err := asSlice("t.multicall", rows)
+	if err != nil {
+		return 0, 0, errors.Wrap(err, "t.multicall XMLRPC call returned unexpected data")
+	}
+	for _, tracker := range trackers {
+		scrape, err := asRow("tracker", tracker, 3)
+		if err != nil {
+			return 0, 0, errors.Wrap(err, "t.multicall XMLRPC call returned unexpected data")
+		}
+		scraped, err := asInt64(TScrapeTimeLast.Cmd(), scrape[0])
+		if err != nil {
+			return 0, 0, errors.Wrap(err, "t.multicall XMLRPC call returned unexpected data")
+		}
+		complete, err := asInt(TScrapeComplete.Cmd(), scrape[1])
+		if err != nil {
+			return 0, 0, errors.Wrap(err, "t.multicall XMLRPC call returned unexpected data")
+		}
+		incomplete, err := asInt(TScrapeIncomplete.Cmd(), scrape[2])
+		if err != nil {
+			return 0, 0, errors.Wrap(err, "t.multicall XMLRPC call returned unexpected data")
+		}
+		if scraped == 0 {
+			continue
+		}
+		if complete > 0 {
+			seeders += complete
+		}
+		if incomplete > 0 {
+			leechers += incomplete
+		}
+	}
+	return seeders, leechers, nil
+}
+
+// SetLabel sets the label on the given Torrent
+func (r *Client) SetLabel(ctx context.Context, t Torrent, newLabel string) error {
+	t.Label = newLabel
+	if _, err := r.callHash(ctx, "d.custom1.set", t.Hash, newLabel); err != nil {
+		return errors.Wrap(err, "d.custom1.set XMLRPC call failed")
+	}
+	return nil
+}
+
+// Custom returns the value of the custom field key of the torrent (d.custom), empty if it is unset
+func (r *Client) Custom(ctx context.Context, t Torrent, key string) (string, error) {
+	results, err := r.callHash(ctx, "d.custom", t.Hash, key)
+	if err != nil {
+		return "", errors.Wrap(err, "d.custom XMLRPC call failed")
+	}
+	return resultString("d.custom", results)
+}
+
+// SetCustom sets the custom field key of the torrent (d.custom.set), it is saved in the session
+func (r *Client) SetCustom(ctx context.Context, t Torrent, key, value string) error {
+	if key == "" {
+		return errors.New("empty custom field key")
+	}
+	if _, err := r.callHash(ctx, "d.custom.set", t.Hash, key, value); err != nil {
+		return errors.Wrap(err, "d.custom.set XMLRPC call failed")
+	}
+	return nil
+}
+
+// CompareAndSetLabel sets the label of the torrent to newLabel only if its current label is expected,
+// and reports whether it was set. rTorrent has no atomic compare-and-set, the label is read and then
+// set in two calls: a concurrent writer changing the label between both calls is overwritten.
+func (r *Client) CompareAndSetLabel(ctx context.Context, t Torrent, expected, newLabel string) (bool, error) {
+	results, err := r.callHash(ctx, "d.custom1", t.Hash)
+	if err != nil {
+		return false, errors.Wrap(err, "d.custom1 XMLRPC call failed")
+	}
+	label, err := resultString("d.custom1", results)
+	if err != nil {
+		return false, err
+	}
+	if label != expected {
+		return false, nil
+	}
+	if err := r.SetLabel(ctx, t, newLabel); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// DefaultLabelDelimiter is the default Config.LabelDelimiter, as used by ruTorrent
+const DefaultLabelDelimiter = ","
+
+// GetLabels returns the labels of the torrent, stored in d.custom1 separated by Config.LabelDelimiter
+func (r *Client) GetLabels(ctx context.Context, t Torrent) ([]string, error) {
+	results, err := r.callHash(ctx, "d.custom1", t.Hash)
+	if err != nil {
+		return nil, errors.Wrap(err, "d.custom1 XMLRPC call failed")
+	}
+	label, err := resultString("d.custom1", results)
+	if err != nil {
+		return nil, err
+	}
+	return r.splitLabels(label), nil
+}
+
+// AddLabel adds label to the labels of the torrent, if it doesn't have it yet. The labels are read
+// and then set in two calls: a concurrent change between both calls is overwritten.
+func (r *Client) AddLabel(ctx context.Context, t Torrent, label string) error {
+	labels, err := r.GetLabels(ctx, t)
+	if err != nil {
+		return err
+	}
+	for _, l := range labels {
+		if l == label {
+			return nil
+		}
+	}
+	return r.SetLabel(ctx, t, strings.Join(append(labels, label), r.labelDelimiter()))
+}
+
+// RemoveLabel removes label from the labels of the torrent, if it has it. The labels are read
+// and then set in two calls: a concurrent change between both calls is overwritten.
+func (r *Client) RemoveLabel(ctx context.Context, t Torrent, label string) error {
+	labels, err := r.GetLabels(ctx, t)
+	if err != nil {
+		return err
+	}
+	kept := labels[:0]
+	for _, l := range labels {
+		if l != label {
+			kept = append(kept, l)
+		}
+	}
+	if len(kept) == len(labels) {
+		return nil
+	}
+	return r.SetLabel(ctx, t, strings.Join(kept, r.labelDelimiter()))
+}
+
+// splitLabels splits d.custom1 into its labels, leaving out empty ones
+func (r *Client) splitLabels(s string) []string {
+	labels := []string{}
+	for _, label := range strings.Split(s, r.labelDelimiter()) {
+		if label = strings.TrimSpace(label); label != "" {
+			labels = append(labels, label)
+		}
+	}
+	return labels
+}
+
+// labelDelimiter returns the configured label delimiter or its default
+func (r *Client) labelDelimiter() string {
+	if r.cfg.LabelDelimiter == "" {
+		return DefaultLabelDelimiter
+	}
+	return r.cfg.LabelDelimiter
+}
+
+// SetLabelMany sets the label on the torrents identified by the given hashes in a single
+// system.multicall. Torrents which could not be labelled are reported in the returned error.
+func (r *Client) SetLabelMany(ctx context.Context, hashes []string, label string) error {
+	return r.callEach(ctx, "d.custom1.set", hashes, label)
+}
+
+// SetPriority sets the download priority of the given Torrent
+func (r *Client) SetPriority(ctx context.Context, t Torrent, p TorrentPriority) error {
+	if _, err := r.callHash(ctx, "d.priority.set", t.Hash, int(p)); err != nil {
+		return errors.Wrap(err, "d.priority.set XMLRPC call failed")
+	}
+	return nil
+}
+
+// ConnectionType is the peer connection mode of a torrent, see SetConnectionType
+type ConnectionType string
+
+const (
+	// ConnectionLeech is the mode of a downloading torrent
+	ConnectionLeech ConnectionType = "leech"
+	// ConnectionSeed is the mode of a complete torrent
+	ConnectionSeed ConnectionType = "seed"
+	// ConnectionInitialSeed is super-seeding: each peer is only offered chunks no other peer has,
+	// to spread a new torrent with less upload
+	ConnectionInitialSeed ConnectionType = "initial_seed"
+)
+
+// Valid reports whether the connection type is one rTorrent accepts
+func (c ConnectionType) Valid() bool {
+	switch c {
+	case ConnectionLeech, ConnectionSeed, ConnectionInitialSeed:
+		return true
+	}
+	return false
+}
+
+// ConnectionType returns the current peer connection mode of the torrent (d.connection_current)
+func (r *Client) ConnectionType(ctx context.Context, t Torrent) (ConnectionType, error) {
+	results, err := r.callHash(ctx, "d.connection_current", t.Hash)
+	if err != nil {
+		return "", errors.Wrap(err, "d.connection_current XMLRPC call failed")
+	}
+	connection, err := resultString("d.connection_current", results)
+	return ConnectionType(connection), err
+}
+
+// SetConnectionType sets the peer connection mode of the torrent (d.connection_current.set), unknown modes
+// are rejected without calling rTorrent. Set ConnectionInitialSeed before starting the torrent.
+func (r *Client) SetConnectionType(ctx context.Context, t Torrent, connection ConnectionType) error {
+	if !connection.Valid() {
+		return errors.Errorf("invalid connection type %q", connection)
+	}
+	if _, err := r.callHash(ctx, "d.connection_current.set", t.Hash, string(connection)); err != nil {
+		return errors.Wrap(err, "d.connection_current.set XMLRPC call failed")
+	}
+	return nil
+}
+
+// IgnoresCommands checks if the torrent is excluded from scheduled commands like ratio rules (d.ignore_commands)
+func (r *Client) IgnoresCommands(ctx context.Context, t Torrent) (bool, error) {
+	results, err := r.callHash(ctx, "d.ignore_commands", t.Hash)
+	if err != nil {
+		return false, errors.Wrap(err, "d.ignore_commands XMLRPC call failed")
+	}
+	ignore, err := resultInt("d.ignore_commands", results)
+	return ignore == 1, err
+}
+
+// SetIgnoreCommands excludes the torrent from scheduled commands like ratio rules, pinning it as a permanent seed
+func (r *Client) SetIgnoreCommands(ctx context.Context, t Torrent, ignore bool) error {
+	value := 0
+	if ignore {
+		value = 1
+	}
+	if _, err := r.callHash(ctx, "d.ignore_commands.set", t.Hash, value); err != nil {
+		return errors.Wrap(err, "d.ignore_commands.set XMLRPC call failed")
+	}
+	return nil
+}
+
+// SetDirectory sets the directory rTorrent looks for the data of the torrent in. For a multi-file
+// torrent the torrent's name is appended to dir, so relocating /old/Show to /new/Show takes "/new".
+// Use SetDirectoryBase to set the exact directory instead. The torrent must be closed, see CloseTorrent.
+func (r *Client) SetDirectory(ctx context.Context, t Torrent, dir string) error {
+	if _, err := r.callHash(ctx, "d.directory.set", t.Hash, dir); err != nil {
+		return errors.Wrap(err, "d.directory.set XMLRPC call failed")
+	}
+	return nil
+}
+
+// SetDirectoryBase sets the directory rTorrent looks for the data of the torrent in, as is: for a
+// multi-file torrent dir is the directory holding its files, e.g. "/new/Show".
+// The torrent must be closed, see CloseTorrent.
+func (r *Client) SetDirectoryBase(ctx context.Context, t Torrent, dir string) error {
+	if _, err := r.callHash(ctx, "d.directory_base.set", t.Hash, dir); err != nil {
+		return errors.Wrap(err, "d.directory_base.set XMLRPC call failed")
+	}
+	return nil
+}
+
+// statusFields are the fields fetched for a Status, in the order expected by decodeStatus
+var statusFields = []Field{DComplete, DCompletedBytes, DDownRate, DUpRate, DRatio, DSizeInBytes, DChunkSize, DSizeChunks, DCompletedChunks, DBytesDone, DHashing, DChunksHashed}
+
+// GetStatus returns the Status for a given Torrent
+func (r *Client) GetStatus(ctx context.Context, t Torrent) (Status, error) {
+	values, err := r.fetchFields(ctx, t.Hash, statusFields)
+	if err != nil {
+		return Status{}, err
+	}
+	return decodeStatus(values)
+}
+
+// WaitFor polls the Status of the torrent every poll interval until pred returns true.
+// It returns ctx.Err() once the context is done, so the overall budget is set through ctx.
+func (r *Client) WaitFor(ctx context.Context, t Torrent, pred func(Status) bool, poll time.Duration) error {
+	if poll <= 0 {
+		return errors.Errorf("invalid poll interval %s", poll)
+	}
+	ticker := time.NewTicker(poll)
+	defer ticker.Stop()
+	for {
+		status, err := r.GetStatus(ctx, t)
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return err
+		}
+		if pred(status) {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// WaitUntilComplete polls the torrent until it completes, see WaitFor
+func (r *Client) WaitUntilComplete(ctx context.Context, t Torrent, poll time.Duration) error {
+	return r.WaitFor(ctx, t, func(s Status) bool { return s.Completed }, poll)
+}
+
+// decodeStatus decodes the values of statusFields for a single torrent
+func decodeStatus(statusData []interface{}) (Status, error) {
+	var s Status
+	complete, err := asInt(DComplete.Cmd(), statusData[0])
+	if err != nil {
+		return s, err
+	}
+	s.Completed = complete > 0
+	if s.CompletedBytes, err = asInt64(DCompletedBytes.Cmd(), statusData[1]); err != nil {
+		return s, err
+	}
+	if s.DownRate, err = asInt(DDownRate.Cmd(), statusData[2]); err != nil {
+		return s, err
+	}
+	if s.UpRate, err = asInt(DUpRate.Cmd(), statusData[3]); err != nil {
+		return s, err
+	}
+	if s.Ratio, err = asRatio(DRatio.Cmd(), statusData[4]); err != nil {
+		return s, err
+	}
+	if s.Size, err = asInt64(DSizeInBytes.Cmd(), statusData[5]); err != nil {
+		return s, err
+	}
+	if s.ChunkSize, err = asInt64(DChunkSize.Cmd(), statusData[6]); err != nil {
+		return s, err
+	}
+	if s.ChunksTotal, err = asInt64(DSizeChunks.Cmd(), statusData[7]); err != nil {
+		return s, err
+	}
+	if s.ChunksDone, err = asInt64(DCompletedChunks.Cmd(), statusData[8]); err != nil {
+		return s, err
+	}
+	if s.BytesDone, err = asInt64(DBytesDone.Cmd(), statusData[9]); err != nil {
+		return s, err
+	}
+	hashing, err := asInt(DHashing.Cmd(), statusData[10])
+	if err != nil {
+		return s, err
+	}
+	s.Hashing = HashingState(hashing)
+	if s.ChunksHashed, err = asInt64(DChunksHashed.Cmd(), statusData[11]); err != nil {
+		return s, err
+	}
+	return s, nil
+}
+
+// fetchFields fetches the given fields of a single torrent in one system.multicall.
+// The values are returned in the order of fields, a fault on any field is returned as an error.
+func (r *Client) fetchFields(ctx context.Context, hash string, fields []Field) ([]interface{}, error) {
+	hash, err := NormalizeHash(hash)
+	if err != nil {
+		return nil, err
+	}
+	calls := make([]xmlrpc.Call, 0, len(fields))
+	for _, field := range fields {
+		calls = append(calls, field.call(hash))
+	}
+	values, err := r.xmlrpcClient.MulticallBatch(ctx, calls)
+	if err != nil {
+		return nil, err
+	}
+	for i, v := range values {
+		if fault, ok := v.(xmlrpc.Fault); ok {
+			if isNotFound(fault) {
+				return nil, errors.Wrap(ErrTorrentNotFound, hash)
+			}
+			return nil, errors.Wrap(fault, fmt.Sprintf("%s XMLRPC call failed", fields[i]))
+		}
+	}
+	return values, nil
+}
+
+// StartTorrent starts the torrent
+func (r *Client) StartTorrent(ctx context.Context, t Torrent) error {
+	_, err := r.callHash(ctx, "d.start", t.Hash)
+	if err != nil {
+		return errors.Wrap(err, "d.start XMLRPC call failed")
+	}
+	return nil
+}
+
+// StopTorrent stops the torrent
+func (r *Client) StopTorrent(ctx context.Context, t Torrent) error {
+	_, err := r.callHash(ctx, "d.stop", t.Hash)
+	if err != nil {
+		return errors.Wrap(err, "d.stop XMLRPC call failed")
+	}
+	return nil
+}
+
+// CloseTorrent closes the torrent
+func (r *Client) CloseTorrent(ctx context.Context, t Torrent) error {
+	_, err := r.callHash(ctx, "d.close", t.Hash)
+	if err != nil {
+		return errors.Wrap(err, "d.close XMLRPC call failed")
+	}
+	return nil
+}
+
+// OpenTorrent opens the torrent
+func (r *Client) OpenTorrent(ctx context.Context, t Torrent) error {
+	_, err := r.callHash(ctx, "d.open", t.Hash)
+	if err != nil {
+		return errors.Wrap(err, "d.open XMLRPC call failed")
+	}
+	return nil
+}
+
+// PauseTorrent pauses the torrent
+func (r *Client) PauseTorrent(ctx context.Context, t Torrent) error {
+	_, err := r.callHash(ctx, "d.pause", t.Hash)
+	if err != nil {
+		return errors.Wrap(err, "d.pause XMLRPC call failed")
+	}
+	return nil
+}
+
+// ResumeTorrent resumes the torrent
+func (r *Client) ResumeTorrent(ctx context.Context, t Torrent) error {
+	_, err := r.callHash(ctx, "d.resume", t.Hash)
+	if err != nil {
+		return errors.Wrap(err, "d.resume XMLRPC call failed")
+	}
+	return nil
+}
+
+// StartMany starts the torrents in a single system.multicall, see StartTorrent.
+// Torrents which could not be started are reported in the returned error.
+func (r *Client) StartMany(ctx context.Context, torrents []Torrent) error {
+	return r.callEach(ctx, "d.start", hashesOf(torrents))
+}
+
+// StopMany stops the torrents in a single system.multicall, see StopTorrent.
+// Torrents which could not be stopped are reported in the returned error.
+func (r *Client) StopMany(ctx context.Context, torrents []Torrent) error {
+	return r.callEach(ctx, "d.stop", hashesOf(torrents))
+}
+
+// PauseMany pauses the torrents in a single system.multicall, see PauseTorrent.
+// Torrents which could not be paused are reported in the returned error.
+func (r *Client) PauseMany(ctx context.Context, torrents []Torrent) error {
+	return r.callEach(ctx, "d.pause", hashesOf(torrents))
+}
+
+// ResumeMany resumes the torrents in a single system.multicall, see ResumeTorrent.
+// Torrents which could not be resumed are reported in the returned error.
+func (r *Client) ResumeMany(ctx context.Context, torrents []Torrent) error {
+	return r.callEach(ctx, "d.resume", hashesOf(torrents))
 }
 
 // IsActive checks if the torrent is active
 func (r *Client) IsActive(ctx context.Context, t Torrent) (bool, error) {
-	results, err := r.xmlrpcClient.Call(ctx, "d.is_active", t.Hash)
+	results, err := r.callHash(ctx, "d.is_active", t.Hash)
 	if err != nil {
 		return false, errors.Wrap(err, "d.is_active XMLRPC call failed")
 	}
 	// active = 1; inactive = 0
-	return results.([]interface{})[0].(int) == 1, nil
+	active, err := resultInt("d.is_active", results)
+	return active == 1, err
+}
+
+// IsMultiFile checks if the torrent contains multiple files, without listing them like GetFiles
+func (r *Client) IsMultiFile(ctx context.Context, t Torrent) (bool, error) {
+	results, err := r.callHash(ctx, DIsMultiFile.Cmd(), t.Hash)
+	if err != nil {
+		return false, errors.Wrap(err, "d.is_multi_file XMLRPC call failed")
+	}
+	multiFile, err := resultInt(DIsMultiFile.Cmd(), results)
+	return multiFile == 1, err
 }
 
 // IsOpen checks if the torrent is open
 func (r *Client) IsOpen(ctx context.Context, t Torrent) (bool, error) {
-	results, err := r.xmlrpcClient.Call(ctx, "d.is_open", t.Hash)
+	results, err := r.callHash(ctx, "d.is_open", t.Hash)
 	if err != nil {
 		return false, errors.Wrap(err, "d.is_open XMLRPC call failed")
 	}
 	// open = 1; closed = 0
-	return results.([]interface{})[0].(int) == 1, nil
+	open, err := resultInt("d.is_open", results)
+	return open == 1, err
 }
 
 // State returns the state that the torrent is into
-// It returns: 0 for stopped, 1 for started/paused
+// It returns: 0 for stopped, 1 for started/paused, see GetState to tell them apart
 func (r *Client) State(ctx context.Context, t Torrent) (int, error) {
-	results, err := r.xmlrpcClient.Call(ctx, "d.state", t.Hash)
+	results, err := r.callHash(ctx, "d.state", t.Hash)
 	if err != nil {
 		return 0, errors.Wrap(err, "d.state XMLRPC call failed")
 	}
-	return results.([]interface{})[0].(int), nil
+	return resultInt("d.state", results)
+}
+
+// TorrentState is the state of a torrent combining d.state, d.is_open, d.is_active and d.hashing, see GetState
+type TorrentState int
+
+const (
+	// TorrentStateClosed is a torrent which is stopped and closed, its files are released
+	TorrentStateClosed TorrentState = iota
+	// TorrentStateStopped is a torrent which is stopped but still open
+	TorrentStateStopped
+	// TorrentStatePaused is a torrent which is started but paused
+	TorrentStatePaused
+	// TorrentStateDownloading is an active torrent which isn't complete
+	TorrentStateDownloading
+	// TorrentStateSeeding is an active torrent which is complete
+	TorrentStateSeeding
+	// TorrentStateHashing is a torrent going through a hash check, whatever its other state
+	TorrentStateHashing
+)
+
+// String returns the name of the torrent state
+func (s TorrentState) String() string {
+	switch s {
+	case TorrentStateClosed:
+		return "closed"
+	case TorrentStateStopped:
+		return "stopped"
+	case TorrentStatePaused:
+		return "paused"
+	case TorrentStateDownloading:
+		return "downloading"
+	case TorrentStateSeeding:
+		return "seeding"
+	case TorrentStateHashing:
+		return "hashing"
+	}
+	return fmt.Sprintf("TorrentState(%d)", int(s))
+}
+
+// stateColumns are the fields fetched by GetState
+var stateColumns = structColumns(reflect.TypeOf(stateRow{}))
+
+// stateRow holds the fields the TorrentState is derived from
+type stateRow struct {
+	Started  bool         `rtorrent:"d.state"`
+	Open     bool         `rtorrent:"d.is_open"`
+	Active   bool         `rtorrent:"d.is_active"`
+	Complete bool         `rtorrent:"d.complete"`
+	Hashing  HashingState `rtorrent:"d.hashing"`
+}
+
+// state returns the TorrentState of the row
+func (row stateRow) state() TorrentState {
+	switch {
+	case row.Hashing != HashingNone:
+		return TorrentStateHashing
+	case !row.Open:
+		return TorrentStateClosed
+	case !row.Started:
+		return TorrentStateStopped
+	case !row.Active:
+		return TorrentStatePaused
+	case row.Complete:
+		return TorrentStateSeeding
+	}
+	return TorrentStateDownloading
+}
+
+// GetState returns the state of the torrent, fetched in a single system.multicall
+func (r *Client) GetState(ctx context.Context, t Torrent) (TorrentState, error) {
+	values, err := r.fetchFields(ctx, t.Hash, columnFields(stateColumns))
+	if err != nil {
+		return TorrentStateClosed, err
+	}
+	var row stateRow
+	if err := decodeColumns("state", values, stateColumns, reflect.ValueOf(&row).Elem()); err != nil {
+		return TorrentStateClosed, errors.Wrap(err, "system.multicall XMLRPC call returned unexpected data")
+	}
+	return row.state(), nil
 }
@@ -1,12 +1,25 @@
 package rtorrent
 
 import (
+	"bytes"
 	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
 	"log/slog"
+	"math"
+	"net/http"
+	"net/http/httptest"
 	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
+	"github.com/autobrr/go-rtorrent/xmlrpc"
+
 	"github.com/pkg/errors"
 	"github.com/stretchr/testify/require"
 )
@@ -32,6 +45,11 @@ func TestRTorrent(t *testing.T) {
 
 	ctx := context.Background()
 
+	t.Run("ping", func(t *testing.T) {
+		err := client.Ping(ctx)
+		require.NoError(t, err)
+	})
+
 	t.Run("get ip", func(t *testing.T) {
 		ctx := context.Background()
 		_, err := client.IP(ctx)
@@ -102,7 +120,7 @@ func TestRTorrent(t *testing.T) {
 				require.Equal(t, "3F9AAC158C7DE8DFCAB171EA58A17AABDF7FBC93", torrents[0].Hash)
 				require.Equal(t, "ubuntu-24.10-desktop-amd64.iso", torrents[0].Name)
 				require.Equal(t, "", torrents[0].Label)
-				require.Equal(t, 5665497088, torrents[0].Size)
+				require.Equal(t, int64(5665497088), torrents[0].Size)
 				require.Equal(t, "/downloads/temp", torrents[0].Path)
 				require.False(t, torrents[0].Completed)
 
@@ -148,22 +166,15 @@ func TestRTorrent(t *testing.T) {
 				})
 
 				t.Run("get status", func(t *testing.T) {
-					var status Status
-					var err error
 					// It may take some time for the download to start
-					retries := maxRetries
-					for i := 0; i <= retries; i++ {
-						<-time.After(time.Second)
-						status, err = client.GetStatus(ctx, torrents[0])
-						require.NoError(t, err)
-						t.Logf("Status = %+v", status)
-						if status.CompletedBytes > 0 {
-							break
-						}
-						if i == retries {
-							require.NoError(t, errors.Errorf("torrent did not start in time"))
-						}
-					}
+					waitCtx, cancel := context.WithTimeout(ctx, time.Duration(maxRetries)*time.Second)
+					defer cancel()
+					err := client.WaitFor(waitCtx, torrents[0], func(s Status) bool { return s.CompletedBytes > 0 }, time.Second)
+					require.NoError(t, err, "torrent did not start in time")
+
+					status, err := client.GetStatus(ctx, torrents[0])
+					require.NoError(t, err)
+					t.Logf("Status = %+v", status)
 
 					require.False(t, status.Completed)
 					require.NotZero(t, status.CompletedBytes)
@@ -230,7 +241,7 @@ func TestRTorrent(t *testing.T) {
 				require.Equal(t, "3F9AAC158C7DE8DFCAB171EA58A17AABDF7FBC93", torrents[0].Hash)
 				require.Equal(t, "ubuntu-24.10-desktop-amd64.iso", torrents[0].Name)
 				require.Equal(t, label.Value, torrents[0].Label)
-				require.Equal(t, 5665497088, torrents[0].Size)
+				require.Equal(t, int64(5665497088), torrents[0].Size)
 				require.Equal(t, "/downloads/temp", torrents[0].Path)
 				require.False(t, torrents[0].Completed)
 
@@ -527,7 +538,7 @@ func TestRTorrent(t *testing.T) {
 				require.Equal(t, "3F9AAC158C7DE8DFCAB171EA58A17AABDF7FBC93", torrents[0].Hash)
 				require.Equal(t, "ubuntu-24.10-desktop-amd64.iso", torrents[0].Name)
 				require.Equal(t, "", torrents[0].Label)
-				require.Equal(t, 5665497088, torrents[0].Size)
+				require.Equal(t, int64(5665497088), torrents[0].Size)
 				require.Equal(t, "/downloads/temp", torrents[0].Path)
 				require.False(t, torrents[0].Completed)
 
@@ -592,7 +603,7 @@ func TestRTorrent(t *testing.T) {
 				require.Equal(t, "3F9AAC158C7DE8DFCAB171EA58A17AABDF7FBC93", torrents[0].Hash)
 				require.Equal(t, "ubuntu-24.10-desktop-amd64.iso", torrents[0].Name)
 				require.Equal(t, label.Value, torrents[0].Label)
-				require.Equal(t, 5665497088, torrents[0].Size)
+				require.Equal(t, int64(5665497088), torrents[0].Size)
 
 				t.Run("delete torrent", func(t *testing.T) {
 					err := client.Delete(ctx, torrents[0])
@@ -638,3 +649,1578 @@ func TestRTorrent(t *testing.T) {
 	})
 
 }
+
+const (
+	testHash  = "3F9AAC158C7DE8DFCAB171EA58A17AABDF7FBC93"
+	testHashA = "AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA"
+	testHashB = "BBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBB"
+)
+
+// rawResponse is a complete XMLRPC response returned verbatim by the test server
+type rawResponse string
+
+// newTestClient returns a Client connected to a server which replies to each method with the given response.
+// Responses are marshalled as XMLRPC values unless they are a rawResponse, a func() interface{}
+// or func(params []interface{}) interface{} is called on every request to produce the response.
+func newTestClient(t *testing.T, responses map[string]interface{}) *Client {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		method, params, _, err := xmlrpc.Unmarshal(r.Body)
+		require.NoError(t, err)
+		response, ok := responses[method]
+		require.True(t, ok, "unexpected method %s", method)
+		w.Header().Set("Content-Type", "text/xml")
+		switch fn := response.(type) {
+		case func() interface{}:
+			response = fn()
+		case func(params []interface{}) interface{}:
+			response = fn(params)
+		}
+		if raw, ok := response.(rawResponse); ok {
+			_, _ = io.WriteString(w, string(raw))
+			return
+		}
+		require.NoError(t, xmlrpc.Marshal(w, "", response))
+	}))
+	t.Cleanup(srv.Close)
+	return NewClient(Config{Addr: srv.URL})
+}
+
+// faultResponse returns the response of a call failing with the fault
+func faultResponse(code int, msg string) rawResponse {
+	var escaped strings.Builder
+	_ = xml.EscapeText(&escaped, []byte(msg))
+	return rawResponse(fmt.Sprintf(`<?xml version="1.0"?>
+<methodResponse><fault><value><struct>
+<member><name>faultCode</name><value><int>%d</int></value></member>
+<member><name>faultString</name><value><string>%s</string></value></member>
+</struct></value></fault></methodResponse>`, code, escaped.String()))
+}
+
+// faultValue returns the fault as found among the results of a system.multicall
+func faultValue(code int, msg string) map[string]interface{} {
+	return map[string]interface{}{"faultCode": code, "faultString": msg}
+}
+
+// notFoundFault is the fault of a call on a torrent rTorrent doesn't know
+var notFoundFault = faultValue(-501, "Could not find info-hash.")
+
+// torrentRow returns the values of torrentFields rTorrent replies with for the torrent,
+// encoded the way decodeTorrent reads them
+func torrentRow(t Torrent) []interface{} {
+	v := reflect.ValueOf(t)
+	row := make([]interface{}, 0, len(torrentColumns))
+	for _, c := range torrentColumns {
+		f := v.Field(c.index)
+		if f.Type() == timeType {
+			var ts int64
+			if tm := f.Interface().(time.Time); !tm.IsZero() {
+				ts = tm.Unix()
+			}
+			if strings.HasPrefix(c.field.Cmd(), "d.custom") {
+				// custom fields are strings, empty when unset
+				if ts == 0 {
+					row = append(row, "")
+				} else {
+					row = append(row, strconv.FormatInt(ts, 10))
+				}
+				continue
+			}
+			row = append(row, int(ts))
+			continue
+		}
+		switch f.Kind() {
+		case reflect.String:
+			row = append(row, f.String())
+		case reflect.Bool:
+			if f.Bool() {
+				row = append(row, 1)
+			} else {
+				row = append(row, 0)
+			}
+		case reflect.Int, reflect.Int64:
+			row = append(row, int(f.Int()))
+		case reflect.Float64:
+			// ratios are in permille
+			row = append(row, int(math.Round(f.Float()*1000)))
+		default:
+			panic("cannot encode " + f.Type().String())
+		}
+	}
+	return row
+}
+
+// setColumn returns a copy of the row of torrentFields with the value of field replaced
+func setColumn(row []interface{}, field Field, v interface{}) []interface{} {
+	row = append([]interface{}{}, row...)
+	for i, f := range torrentFields {
+		if f == field {
+			row[i] = v
+			return row
+		}
+	}
+	panic("not a torrent field: " + string(field))
+}
+
+func TestVersions(t *testing.T) {
+	t.Run("all", func(t *testing.T) {
+		client := newTestClient(t, map[string]interface{}{"system.multicall": rawResponse(`<?xml version="1.0"?>
+<methodResponse><params><param><value><array><data>
+<value><array><data><value><string>0.15.1</string></value></data></array></value>
+<value><array><data><value><string>0.15.1</string></value></data></array></value>
+<value><array><data><value><i8>10</i8></value></data></array></value>
+</data></array></value></param></params></methodResponse>`)})
+
+		versions, err := client.Versions(context.Background())
+		require.NoError(t, err)
+		require.Equal(t, Versions{ClientVersion: "0.15.1", LibraryVersion: "0.15.1", APIVersion: "10"}, versions)
+	})
+
+	t.Run("missing api version", func(t *testing.T) {
+		client := newTestClient(t, map[string]interface{}{
+			"system.multicall": []interface{}{
+				[]interface{}{"0.9.6"}, []interface{}{"0.13.6"},
+				faultValue(-506, "Method 'system.api_version' not defined"),
+			},
+		})
+
+		versions, err := client.Versions(context.Background())
+		require.NoError(t, err)
+		require.Equal(t, Versions{ClientVersion: "0.9.6", LibraryVersion: "0.13.6"}, versions)
+	})
+}
+
+func TestProcessInfo(t *testing.T) {
+	t.Run("all", func(t *testing.T) {
+		client := newTestClient(t, map[string]interface{}{
+			"system.multicall": []interface{}{
+				[]interface{}{1234}, []interface{}{"/home/rtorrent"}, []interface{}{"seedbox"}, []interface{}{1700000000},
+			},
+		})
+
+		info, err := client.ProcessInfo(context.Background())
+		require.NoError(t, err)
+		require.Equal(t, ProcessInfo{PID: 1234, Cwd: "/home/rtorrent", Hostname: "seedbox", Started: time.Unix(1700000000, 0)}, info)
+		require.Greater(t, info.Uptime(), time.Duration(0))
+	})
+
+	t.Run("no startup time", func(t *testing.T) {
+		client := newTestClient(t, map[string]interface{}{
+			"system.multicall": []interface{}{
+				[]interface{}{1234}, []interface{}{"/home/rtorrent"}, []interface{}{"seedbox"},
+				faultValue(-506, "Method 'system.startup_time' not defined"),
+			},
+		})
+
+		info, err := client.ProcessInfo(context.Background())
+		require.NoError(t, err)
+		require.True(t, info.Started.IsZero())
+		require.Zero(t, info.Uptime())
+	})
+}
+
+func TestServerTime(t *testing.T) {
+	client := newTestClient(t, map[string]interface{}{"system.time": 1700000000})
+
+	now, err := client.ServerTime(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, time.Unix(1700000000, 0), now)
+}
+
+func TestSCGIInfo(t *testing.T) {
+	client := newTestClient(t, map[string]interface{}{
+		"system.multicall": []interface{}{[]interface{}{1}, []interface{}{"0.0.0.0"}, []interface{}{""}},
+	})
+
+	info, err := client.SCGIInfo(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, SCGIInfo{DontRoute: true, BindAddress: "0.0.0.0"}, info)
+}
+
+func TestGetTorrentsEmpty(t *testing.T) {
+	// rTorrent answers an empty view with an empty array, some versions with a bare empty value instead
+	for name, value := range map[string]string{
+		"array":  `<array><data></data></array>`,
+		"string": `<string></string>`,
+		"bare":   ``,
+		"nil":    `<nil/>`,
+	} {
+		t.Run(name, func(t *testing.T) {
+			response := rawResponse(`<?xml version="1.0"?>
+<methodResponse><params><param><value>` + value + `</value></param></params></methodResponse>`)
+			client := newTestClient(t, map[string]interface{}{
+				"d.multicall2": response,
+				"f.multicall":  response,
+				"view.list":    []interface{}{"main", "default"},
+			})
+
+			torrents, err := client.GetTorrents(context.Background(), ViewMain)
+			require.NoError(t, err)
+			require.Equal(t, []Torrent{}, torrents)
+
+			files, err := client.GetFiles(context.Background(), Torrent{Hash: testHash})
+			require.NoError(t, err)
+			require.Equal(t, []File{}, files)
+		})
+	}
+}
+
+func TestUnknownView(t *testing.T) {
+	t.Run("fault", func(t *testing.T) {
+		client := newTestClient(t, map[string]interface{}{
+			"d.multicall2": faultResponse(-500, "Could not find view: maim"),
+		})
+
+		_, err := client.GetTorrents(context.Background(), "maim")
+		require.ErrorIs(t, err, ErrUnknownView)
+	})
+
+	t.Run("empty", func(t *testing.T) {
+		client := newTestClient(t, map[string]interface{}{
+			"d.multicall2":         []interface{}{},
+			"d.multicall.filtered": []interface{}{},
+			"view.list":            []interface{}{"main", "default", "seeding"},
+		})
+
+		torrents, err := client.GetTorrents(context.Background(), ViewSeeding)
+		require.NoError(t, err)
+		require.Empty(t, torrents)
+
+		_, err = client.GetTorrents(context.Background(), "maim")
+		require.ErrorIs(t, err, ErrUnknownView)
+		_, err = client.GetTorrentsFiltered(context.Background(), "maim", "d.complete=")
+		require.ErrorIs(t, err, ErrUnknownView)
+
+		valid, err := client.IsValidView(context.Background(), ViewMain)
+		require.NoError(t, err)
+		require.True(t, valid)
+		valid, err = client.IsValidView(context.Background(), "maim")
+		require.NoError(t, err)
+		require.False(t, valid)
+	})
+}
+
+func TestGetTorrentsPage(t *testing.T) {
+	torrent := func(hash string) []interface{} {
+		return torrentRow(Torrent{
+			Hash: hash, Name: "name-" + hash, Path: "/downloads", Size: 1024, Completed: true, Ratio: 0.5,
+			Created: time.Unix(1700000000, 0), Started: time.Unix(1700000100, 0),
+			Priority: TorrentPriorityNormal, FileCount: 1,
+		})
+	}
+	multicall := func(torrents ...[]interface{}) []interface{} {
+		var values []interface{}
+		for _, torrent := range torrents {
+			for _, v := range torrent {
+				if fault, ok := v.(map[string]interface{}); ok {
+					values = append(values, fault)
+					continue
+				}
+				values = append(values, []interface{}{v})
+			}
+		}
+		return values
+	}
+	gone := make([]interface{}, len(torrentFields))
+	for i := range gone {
+		gone[i] = notFoundFault
+	}
+
+	unknown := setColumn(torrent("C"), DSizeInBytes, faultValue(-506, "Method 'd.size_bytes' not defined"))
+
+	page := multicall(torrent("B"), gone)
+	client := newTestClient(t, map[string]interface{}{
+		"download_list":    []interface{}{"A", "B", "C", "D", "E", "F"},
+		"system.multicall": func() interface{} { return page },
+		"d.multicall2":     []interface{}{torrent("A"), torrent("B"), torrent("D"), torrent("E"), torrent("F")},
+	})
+
+	torrents, err := client.GetTorrentsPage(context.Background(), ViewMain, 1, 2)
+	require.NoError(t, err)
+	require.Len(t, torrents, 1)
+	require.Equal(t, "B", torrents[0].Hash)
+	require.Equal(t, "name-B", torrents[0].Name)
+	require.Equal(t, 0.5, torrents[0].Ratio)
+
+	// any other fault than a removed torrent is returned rather than dropping the torrent
+	page = multicall(torrent("B"), unknown)
+	_, err = client.GetTorrentsPage(context.Background(), ViewMain, 1, 2)
+	require.ErrorContains(t, err, "d.size_bytes XMLRPC call failed")
+
+	// most of the view is fetched in a single d.multicall2, C was removed in the meantime
+	torrents, err = client.GetTorrentsPage(context.Background(), ViewMain, 1, 4)
+	require.NoError(t, err)
+	require.Len(t, torrents, 3)
+	require.Equal(t, []string{"B", "D", "E"}, []string{torrents[0].Hash, torrents[1].Hash, torrents[2].Hash})
+
+	torrents, err = client.GetTorrentsPage(context.Background(), ViewMain, 10, 2)
+	require.NoError(t, err)
+	require.Empty(t, torrents)
+
+	_, err = client.GetTorrentsPage(context.Background(), ViewMain, -1, 2)
+	require.Error(t, err)
+}
+
+func TestViewSize(t *testing.T) {
+	client := newTestClient(t, map[string]interface{}{
+		"view.size": func(params []interface{}) interface{} {
+			require.Equal(t, []interface{}{"", "seeding"}, params)
+			return 42
+		},
+		"system.multicall": func(params []interface{}) interface{} {
+			results := []interface{}{[]interface{}{50}, []interface{}{42}}
+			if len(params[0].([]interface{})) == 3 {
+				results = append(results, faultValue(-500, "Could not find view: custom"))
+			}
+			return results
+		},
+	})
+
+	size, err := client.ViewSize(context.Background(), ViewSeeding)
+	require.NoError(t, err)
+	require.Equal(t, 42, size)
+
+	sizes, err := client.ViewSizes(context.Background(), ViewMain, ViewSeeding)
+	require.NoError(t, err)
+	require.Equal(t, map[View]int{ViewMain: 50, ViewSeeding: 42}, sizes)
+
+	_, err = client.ViewSizes(context.Background(), ViewMain, ViewSeeding, "custom")
+	require.ErrorContains(t, err, "view.size XMLRPC call failed for custom")
+}
+
+func TestTorrentCount(t *testing.T) {
+	client := newTestClient(t, map[string]interface{}{
+		"view.size": func(params []interface{}) interface{} {
+			require.Equal(t, []interface{}{"", "main"}, params)
+			return 1234
+		},
+	})
+
+	count, err := client.TorrentCount(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, 1234, count)
+}
+
+func TestGetTorrentsFiltered(t *testing.T) {
+	require.Equal(t, `equal={d.custom1=,cat="my label"}`, FilterLabelEquals("my label"))
+	require.Equal(t, `equal={d.custom1=,cat="a\"b\\c"}`, FilterLabelEquals(`a"b\c`))
+
+	client := newTestClient(t, map[string]interface{}{
+		"d.multicall.filtered": []interface{}{
+			torrentRow(Torrent{Hash: "HASH", Name: "name", Label: "my label", Completed: true}),
+		},
+	})
+
+	torrents, err := client.GetTorrentsFiltered(context.Background(), ViewMain, FilterLabelEquals("my label"))
+	require.NoError(t, err)
+	require.Len(t, torrents, 1)
+	require.Equal(t, "my label", torrents[0].Label)
+}
+
+func TestGetTorrentsByLabel(t *testing.T) {
+	row := func(hash, label string) []interface{} {
+		return torrentRow(Torrent{Hash: hash, Name: "name", Label: label})
+	}
+
+	t.Run("filtered", func(t *testing.T) {
+		client := newTestClient(t, map[string]interface{}{
+			"d.multicall.filtered": func(params []interface{}) interface{} {
+				require.Equal(t, FilterLabelEquals("tv"), params[2])
+				return []interface{}{row(testHashA, "tv")}
+			},
+		})
+
+		torrents, err := client.GetTorrentsByLabel(context.Background(), ViewMain, "tv")
+		require.NoError(t, err)
+		require.Len(t, torrents, 1)
+		require.Equal(t, testHashA, torrents[0].Hash)
+	})
+
+	t.Run("client-side", func(t *testing.T) {
+		client := newTestClient(t, map[string]interface{}{
+			"d.multicall.filtered": faultResponse(-506, "Method 'd.multicall.filtered' not defined"),
+			"d.multicall2":         []interface{}{row(testHashA, "tv"), row(testHashB, "movies")},
+		})
+
+		torrents, err := client.GetTorrentsByLabel(context.Background(), ViewMain, "tv")
+		require.NoError(t, err)
+		require.Len(t, torrents, 1)
+		require.Equal(t, testHashA, torrents[0].Hash)
+	})
+}
+
+func TestGetCompletedTorrents(t *testing.T) {
+	row := func(hash string, completed bool) []interface{} {
+		return torrentRow(Torrent{Hash: hash, Name: "name", Completed: completed})
+	}
+
+	t.Run("view", func(t *testing.T) {
+		client := newTestClient(t, map[string]interface{}{
+			"d.multicall2": func(params []interface{}) interface{} {
+				require.Equal(t, "complete", params[1])
+				return []interface{}{row(testHashA, true)}
+			},
+		})
+
+		torrents, err := client.GetCompletedTorrents(context.Background())
+		require.NoError(t, err)
+		require.Len(t, torrents, 1)
+		require.Equal(t, testHashA, torrents[0].Hash)
+	})
+
+	t.Run("no view", func(t *testing.T) {
+		client := newTestClient(t, map[string]interface{}{
+			"d.multicall2": func(params []interface{}) interface{} {
+				if params[1] != "main" {
+					return faultResponse(-500, "Could not find view: "+params[1].(string))
+				}
+				return []interface{}{row(testHashA, true), row(testHashB, false)}
+			},
+		})
+
+		torrents, err := client.GetCompletedTorrents(context.Background())
+		require.NoError(t, err)
+		require.Len(t, torrents, 1)
+		require.Equal(t, testHashA, torrents[0].Hash)
+
+		torrents, err = client.GetIncompleteTorrents(context.Background())
+		require.NoError(t, err)
+		require.Len(t, torrents, 1)
+		require.Equal(t, testHashB, torrents[0].Hash)
+	})
+}
+
+func TestGetTorrentsSorted(t *testing.T) {
+	client := newTestClient(t, map[string]interface{}{
+		"d.multicall2": []interface{}{
+			torrentRow(Torrent{Hash: "A", Name: "a", Size: 300}),
+			torrentRow(Torrent{Hash: "B", Name: "b", Size: 100, Created: time.Unix(1700000000, 0)}),
+			torrentRow(Torrent{Hash: "C", Name: "c", Size: 200}),
+		},
+	})
+
+	hashes := func(torrents []Torrent) []string {
+		var hashes []string
+		for _, torrent := range torrents {
+			hashes = append(hashes, torrent.Hash)
+		}
+		return hashes
+	}
+
+	torrents, err := client.GetTorrentsSorted(context.Background(), ViewMain, DSizeInBytes, true)
+	require.NoError(t, err)
+	require.Equal(t, []string{"B", "C", "A"}, hashes(torrents))
+
+	torrents, err = client.GetTorrentsSorted(context.Background(), ViewMain, DSizeInBytes, false)
+	require.NoError(t, err)
+	require.Equal(t, []string{"A", "C", "B"}, hashes(torrents))
+
+	// zero timestamps keep their original order
+	torrents, err = client.GetTorrentsSorted(context.Background(), ViewMain, DCreationTime, true)
+	require.NoError(t, err)
+	require.Equal(t, []string{"A", "C", "B"}, hashes(torrents))
+
+	_, err = client.GetTorrentsSorted(context.Background(), ViewMain, DUpRate, true)
+	require.Error(t, err)
+}
+
+func TestGetStatus(t *testing.T) {
+	t.Run("batched", func(t *testing.T) {
+		client := newTestClient(t, map[string]interface{}{
+			"system.multicall": []interface{}{
+				[]interface{}{0}, []interface{}{3072}, []interface{}{100}, []interface{}{50}, []interface{}{250},
+				[]interface{}{4096}, []interface{}{1024}, []interface{}{4}, []interface{}{3}, []interface{}{2048},
+				[]interface{}{1}, []interface{}{2},
+			},
+		})
+
+		status, err := client.GetStatus(context.Background(), Torrent{Hash: testHash})
+		require.NoError(t, err)
+		require.Equal(t, Status{
+			CompletedBytes: 3072,
+			DownRate:       100,
+			UpRate:         50,
+			Ratio:          0.25,
+			Size:           4096,
+			ChunkSize:      1024,
+			ChunksTotal:    4,
+			ChunksDone:     3,
+			BytesDone:      2048,
+			Hashing:        HashingInitial,
+			ChunksHashed:   2,
+		}, status)
+		require.Equal(t, 0.5, status.HashProgress())
+		require.Equal(t, "initial", status.Hashing.String())
+	})
+
+	t.Run("fault", func(t *testing.T) {
+		values := make([]interface{}, len(statusFields))
+		for i := range values {
+			values[i] = faultValue(-506, "Method 'd.complete' not defined")
+		}
+		client := newTestClient(t, map[string]interface{}{"system.multicall": values})
+
+		_, err := client.GetStatus(context.Background(), Torrent{Hash: testHash})
+		require.ErrorContains(t, err, "d.complete XMLRPC call failed: -506: Method 'd.complete' not defined")
+		require.NotErrorIs(t, err, ErrTorrentNotFound)
+	})
+}
+
+func TestStatusETA(t *testing.T) {
+	require.Equal(t, 10*time.Second, Status{Size: 2048, CompletedBytes: 1024, DownRate: 100}.ETA())
+	require.Equal(t, time.Duration(0), Status{Completed: true, Size: 2048, CompletedBytes: 2048}.ETA())
+	require.Equal(t, time.Duration(0), Status{Size: 2048, CompletedBytes: 2048}.ETA())
+	require.Equal(t, ETAUnknown, Status{Size: 2048, CompletedBytes: 1024}.ETA())
+}
+
+func TestDownloadDuration(t *testing.T) {
+	started := time.Unix(1700000000, 0)
+
+	d, ok := Torrent{Started: started, Finished: started.Add(time.Hour)}.DownloadDuration()
+	require.True(t, ok)
+	require.Equal(t, time.Hour, d)
+
+	_, ok = Torrent{Started: started}.DownloadDuration()
+	require.False(t, ok)
+	_, ok = Torrent{Finished: started}.DownloadDuration()
+	require.False(t, ok)
+	_, ok = Torrent{Started: started.Add(time.Hour), Finished: started}.DownloadDuration()
+	require.False(t, ok)
+}
+
+func TestFormatBytes(t *testing.T) {
+	require.Equal(t, "0 B", FormatBytes(0))
+	require.Equal(t, "1023 B", FormatBytes(1023))
+	require.Equal(t, "1.0 KiB", FormatBytes(1024))
+	require.Equal(t, "5.3 GiB", FormatBytes(5665497088))
+	require.Equal(t, "8.0 EiB", FormatBytes(math.MaxInt64))
+	require.Equal(t, "2.1 MiB/s", FormatRate(2202010))
+}
+
+func TestWaitFor(t *testing.T) {
+	client := newTestClient(t, map[string]interface{}{
+		"system.multicall": []interface{}{
+			[]interface{}{0}, []interface{}{0}, []interface{}{0}, []interface{}{0}, []interface{}{0},
+			[]interface{}{4096}, []interface{}{1024}, []interface{}{4}, []interface{}{0}, []interface{}{0},
+			[]interface{}{0}, []interface{}{0},
+		},
+	})
+
+	err := client.WaitFor(context.Background(), Torrent{Hash: testHash}, func(s Status) bool { return s.Size == 4096 }, time.Millisecond)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	err = client.WaitUntilComplete(ctx, Torrent{Hash: testHash}, 10*time.Millisecond)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	err = client.WaitUntilComplete(context.Background(), Torrent{Hash: testHash}, 0)
+	require.EqualError(t, err, "invalid poll interval 0s")
+	err = client.WaitFor(context.Background(), Torrent{Hash: testHash}, func(Status) bool { return true }, -time.Second)
+	require.EqualError(t, err, "invalid poll interval -1s")
+}
+
+func TestWatch(t *testing.T) {
+	var mu sync.Mutex
+	calls := 0
+	client := newTestClient(t, map[string]interface{}{
+		"d.multicall2": func() interface{} {
+			mu.Lock()
+			defer mu.Unlock()
+			calls++
+			label := "first"
+			if calls >= 3 {
+				label = "second"
+			}
+			return []interface{}{
+				torrentRow(Torrent{Hash: "A", Name: "a", Label: label}),
+			}
+		},
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	ch, err := client.Watch(ctx, ViewMain, 5*time.Millisecond)
+	require.NoError(t, err)
+
+	torrents := <-ch
+	require.Equal(t, "first", torrents[0].Label)
+	// the second poll is unchanged and must not be emitted
+	torrents = <-ch
+	require.Equal(t, "second", torrents[0].Label)
+
+	cancel()
+	for range ch {
+	}
+
+	_, err = client.Watch(context.Background(), ViewMain, 0)
+	require.EqualError(t, err, "invalid watch interval 0s")
+}
+
+// lineWriter sends every write, e.g. a log record, to the channel
+type lineWriter chan string
+
+func (w lineWriter) Write(p []byte) (int, error) {
+	w <- string(p)
+	return len(p), nil
+}
+
+func TestWatchLogsErrors(t *testing.T) {
+	var mu sync.Mutex
+	calls := 0
+	client := newTestClient(t, map[string]interface{}{
+		"d.multicall2": func() interface{} {
+			mu.Lock()
+			defer mu.Unlock()
+			calls++
+			if calls == 1 {
+				return []interface{}{
+					torrentRow(Torrent{Hash: "A", Name: "a"}),
+				}
+			}
+			return faultResponse(-501, "Internal error")
+		},
+	})
+	records := make(lineWriter, 16)
+	client.cfg.Logger = slog.New(slog.NewTextHandler(records, &slog.HandlerOptions{
+		ReplaceAttr: func(_ []string, a slog.Attr) slog.Attr {
+			if a.Key == slog.TimeKey {
+				return slog.Attr{}
+			}
+			return a
+		},
+	}))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	ch, err := client.Watch(ctx, ViewMain, 5*time.Millisecond)
+	require.NoError(t, err)
+	<-ch
+
+	require.Equal(t, "level=ERROR msg=watch view=main error=\"d.multicall2 XMLRPC call failed: -501: Internal error\"\n", <-records)
+	cancel()
+	for range ch {
+	}
+}
+
+func TestDeleteWithData(t *testing.T) {
+	files := []interface{}{
+		[]interface{}{"e01.mkv", 2048, 0, 1, 1, 0, 1},
+		[]interface{}{"extras/sample.mkv", 2048, 2048, 1, 1, 1, 2},
+		[]interface{}{"extras/subs/en.srt", 1024, 4096, 1, 1, 2, 3},
+	}
+	for _, tt := range []struct {
+		name      string
+		basePath  string
+		directory string
+		multiFile int
+		files     []interface{}
+		removed   []string
+		pruned    []interface{}
+	}{
+		{
+			name: "single file", basePath: "/downloads/Show", multiFile: 0,
+			removed: []string{"/downloads/Show"},
+		},
+		{
+			name: "closed single file", directory: "/downloads", multiFile: 0,
+			removed: []string{"/downloads/Show"},
+		},
+		{
+			name: "multi file", basePath: "/downloads/Show", multiFile: 1, files: files,
+			removed: []string{"/downloads/Show/e01.mkv", "/downloads/Show/extras/sample.mkv", "/downloads/Show/extras/subs/en.srt"},
+			pruned:  []interface{}{"", "rmdir", "--", "/downloads/Show/extras/subs", "/downloads/Show/extras", "/downloads/Show"},
+		},
+		{
+			// set with SetDirectoryBase, the shared download directory itself is kept
+			name: "multi file in shared directory", basePath: "/downloads", multiFile: 1, files: files,
+			removed: []string{"/downloads/e01.mkv", "/downloads/extras/sample.mkv", "/downloads/extras/subs/en.srt"},
+			pruned:  []interface{}{"", "rmdir", "--", "/downloads/extras/subs", "/downloads/extras"},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			var mu sync.Mutex
+			var methods, removed []string
+			var pruned []interface{}
+			client := newTestClient(t, map[string]interface{}{
+				"system.multicall": func(params []interface{}) interface{} {
+					calls := params[0].([]interface{})
+					if calls[0].(map[string]interface{})["methodName"] == "d.base_path" {
+						return []interface{}{
+							[]interface{}{tt.basePath}, []interface{}{tt.directory}, []interface{}{"Show"}, []interface{}{tt.multiFile},
+						}
+					}
+					mu.Lock()
+					defer mu.Unlock()
+					methods = append(methods, "rm")
+					var results []interface{}
+					for _, call := range calls {
+						params := call.(map[string]interface{})["params"].([]interface{})
+						require.Equal(t, []interface{}{"", "rm", "-f", "--"}, params[:4])
+						removed = append(removed, params[4].(string))
+						results = append(results, []interface{}{""})
+					}
+					return results
+				},
+				"f.multicall": tt.files,
+				"d.close":     func() interface{} { methods = append(methods, "d.close"); return 0 },
+				"d.erase":     func() interface{} { methods = append(methods, "d.erase"); return 0 },
+				"execute.nothrow": func(params []interface{}) interface{} {
+					methods = append(methods, "rmdir")
+					pruned = params
+					return 0
+				},
+			})
+
+			err := client.DeleteWithData(context.Background(), Torrent{Hash: testHash})
+			require.NoError(t, err)
+			want := []string{"d.close", "rm", "rmdir", "d.erase"}
+			if tt.pruned == nil {
+				want = []string{"d.close", "rm", "d.erase"}
+			}
+			require.Equal(t, want, methods)
+			require.Equal(t, tt.removed, removed)
+			require.Equal(t, tt.pruned, pruned)
+		})
+	}
+
+	t.Run("files left behind", func(t *testing.T) {
+		erased := false
+		client := newTestClient(t, map[string]interface{}{
+			"system.multicall": func(params []interface{}) interface{} {
+				calls := params[0].([]interface{})
+				if calls[0].(map[string]interface{})["methodName"] == "d.base_path" {
+					return []interface{}{
+						[]interface{}{"/downloads/Show"}, []interface{}{""}, []interface{}{"Show"}, []interface{}{1},
+					}
+				}
+				return []interface{}{
+					[]interface{}{""},
+					faultValue(-1, "rm: cannot remove: Permission denied"),
+					[]interface{}{""},
+				}
+			},
+			"f.multicall": files,
+			"d.close":     0,
+			"d.erase":     func() interface{} { erased = true; return 0 },
+		})
+
+		err := client.DeleteWithData(context.Background(), Torrent{Hash: testHash})
+		require.EqualError(t, err, "could not delete 1 files of "+testHash+": /downloads/Show/extras/sample.mkv")
+		require.False(t, erased, "the torrent must stay loaded so the delete can be retried")
+	})
+
+	t.Run("invalid paths", func(t *testing.T) {
+		for _, tt := range []struct {
+			basePath string
+			file     string
+		}{
+			{basePath: "/", file: "e01.mkv"},
+			{basePath: "downloads/Show", file: "e01.mkv"},
+			{basePath: "/downloads/Show", file: "../../etc/passwd"},
+		} {
+			client := newTestClient(t, map[string]interface{}{
+				"system.multicall": []interface{}{
+					[]interface{}{tt.basePath}, []interface{}{""}, []interface{}{"Show"}, []interface{}{1},
+				},
+				"f.multicall": []interface{}{[]interface{}{tt.file, 2048, 0, 1, 1, 0, 1}},
+			})
+
+			err := client.DeleteWithData(context.Background(), Torrent{Hash: testHash})
+			require.ErrorContains(t, err, "refusing to delete data of "+testHash, tt.basePath+tt.file)
+		}
+	})
+}
+
+func TestDeleteWithOptions(t *testing.T) {
+	for _, tt := range []struct {
+		name string
+		opts DeleteOptions
+		want []string
+	}{
+		{name: "default", opts: DeleteOptions{}, want: []string{"d.erase"}},
+		{name: "keep tied file", opts: DeleteOptions{KeepTiedFile: true}, want: []string{"d.tied_to_file.set", "d.erase"}},
+		{name: "remove tied file", opts: DeleteOptions{RemoveTiedFile: true}, want: []string{"d.delete_tied", "d.erase"}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			var methods []string
+			record := func(method string) func() interface{} {
+				return func() interface{} {
+					methods = append(methods, method)
+					return 0
+				}
+			}
+			client := newTestClient(t, map[string]interface{}{
+				"d.tied_to_file.set": record("d.tied_to_file.set"),
+				"d.delete_tied":      record("d.delete_tied"),
+				"d.erase":            record("d.erase"),
+			})
+
+			err := client.DeleteWithOptions(context.Background(), Torrent{Hash: testHash}, tt.opts)
+			require.NoError(t, err)
+			require.Equal(t, tt.want, methods)
+		})
+	}
+}
+
+func TestDeleteMissing(t *testing.T) {
+	notFound := faultResponse(-501, "Could not find info-hash.")
+	client := newTestClient(t, map[string]interface{}{
+		"d.tied_to_file.set": notFound,
+		"d.erase":            notFound,
+		"system.multicall": []interface{}{
+			notFoundFault,
+			notFoundFault,
+			notFoundFault,
+			notFoundFault,
+		},
+	})
+	torrent := Torrent{Hash: testHash}
+
+	for _, opts := range []DeleteOptions{
+		{IgnoreMissing: true},
+		{KeepTiedFile: true, IgnoreMissing: true},
+		{RemoveData: true, IgnoreMissing: true},
+	} {
+		require.NoError(t, client.DeleteWithOptions(context.Background(), torrent, opts))
+	}
+
+	err := client.Delete(context.Background(), torrent)
+	require.ErrorContains(t, err, "Could not find info-hash")
+	err = client.DeleteWithOptions(context.Background(), torrent, DeleteOptions{KeepTiedFile: true})
+	require.ErrorContains(t, err, "Could not find info-hash")
+	err = client.DeleteWithData(context.Background(), torrent)
+	require.ErrorIs(t, err, ErrTorrentNotFound)
+
+	err = client.DeleteWithOptions(context.Background(), torrent, DeleteOptions{RemoveTiedFile: true, KeepTiedFile: true})
+	require.EqualError(t, err, "delete options RemoveTiedFile and KeepTiedFile contradict each other")
+}
+
+func TestDeleteMany(t *testing.T) {
+	client := newTestClient(t, map[string]interface{}{
+		"system.multicall": []interface{}{
+			[]interface{}{0},
+			notFoundFault,
+		},
+	})
+
+	err := client.DeleteMany(context.Background(), []Torrent{{Hash: testHashA}, {Hash: testHashB}})
+	require.EqualError(t, err, "d.erase XMLRPC call failed for 1 torrents: "+testHashB+": -501: Could not find info-hash.")
+
+	require.NoError(t, client.DeleteMany(context.Background(), nil))
+}
+
+func TestStartMany(t *testing.T) {
+	var calls []interface{}
+	client := newTestClient(t, map[string]interface{}{
+		"system.multicall": func(params []interface{}) interface{} {
+			calls = params[0].([]interface{})
+			return []interface{}{
+				notFoundFault,
+				[]interface{}{0},
+			}
+		},
+	})
+
+	err := client.StartMany(context.Background(), []Torrent{{Hash: testHashA}, {Hash: testHashB}})
+	require.EqualError(t, err, "d.start XMLRPC call failed for 1 torrents: "+testHashA+": -501: Could not find info-hash.")
+	require.Equal(t, []interface{}{
+		map[string]interface{}{"methodName": "d.start", "params": []interface{}{testHashA}},
+		map[string]interface{}{"methodName": "d.start", "params": []interface{}{testHashB}},
+	}, calls)
+}
+
+func TestSetLabelMany(t *testing.T) {
+	var calls []interface{}
+	client := newTestClient(t, map[string]interface{}{
+		"system.multicall": func(params []interface{}) interface{} {
+			calls = params[0].([]interface{})
+			return []interface{}{[]interface{}{0}, []interface{}{0}}
+		},
+	})
+
+	err := client.SetLabelMany(context.Background(), []string{testHashA, testHashB}, "TestLabel")
+	require.NoError(t, err)
+	require.Equal(t, []interface{}{
+		map[string]interface{}{"methodName": "d.custom1.set", "params": []interface{}{testHashA, "TestLabel"}},
+		map[string]interface{}{"methodName": "d.custom1.set", "params": []interface{}{testHashB, "TestLabel"}},
+	}, calls)
+}
+
+func TestCustom(t *testing.T) {
+	custom := map[string]string{}
+	client := newTestClient(t, map[string]interface{}{
+		"d.custom": func(params []interface{}) interface{} { return custom[params[1].(string)] },
+		"d.custom.set": func(params []interface{}) interface{} {
+			custom[params[1].(string)] = params[2].(string)
+			return 0
+		},
+	})
+	torrent := Torrent{Hash: testHash}
+
+	require.NoError(t, client.SetCustom(context.Background(), torrent, "seedingtime", "1700000100"))
+	value, err := client.Custom(context.Background(), torrent, "seedingtime")
+	require.NoError(t, err)
+	require.Equal(t, "1700000100", value)
+
+	value, err = client.Custom(context.Background(), torrent, "unset")
+	require.NoError(t, err)
+	require.Empty(t, value)
+
+	require.EqualError(t, client.SetCustom(context.Background(), torrent, "", "x"), "empty custom field key")
+}
+
+func TestGetState(t *testing.T) {
+	tests := []struct {
+		started, open, active, complete, hashing int
+		want                                     TorrentState
+	}{
+		{0, 0, 0, 0, 0, TorrentStateClosed},
+		{0, 1, 0, 1, 0, TorrentStateStopped},
+		{1, 1, 0, 0, 0, TorrentStatePaused},
+		{1, 1, 1, 0, 0, TorrentStateDownloading},
+		{1, 1, 1, 1, 0, TorrentStateSeeding},
+		{1, 1, 1, 0, 1, TorrentStateHashing},
+	}
+	for _, tt := range tests {
+		t.Run(tt.want.String(), func(t *testing.T) {
+			client := newTestClient(t, map[string]interface{}{
+				"system.multicall": []interface{}{
+					[]interface{}{tt.started}, []interface{}{tt.open}, []interface{}{tt.active}, []interface{}{tt.complete}, []interface{}{tt.hashing},
+				},
+			})
+
+			state, err := client.GetState(context.Background(), Torrent{Hash: testHash})
+			require.NoError(t, err)
+			require.Equal(t, tt.want, state)
+		})
+	}
+}
+
+func TestIgnoreCommands(t *testing.T) {
+	ignore := 0
+	client := newTestClient(t, map[string]interface{}{
+		"d.ignore_commands": func() interface{} { return ignore },
+		"d.ignore_commands.set": func(params []interface{}) interface{} {
+			ignore = params[1].(int)
+			return 0
+		},
+	})
+	torrent := Torrent{Hash: testHash}
+
+	ignored, err := client.IgnoresCommands(context.Background(), torrent)
+	require.NoError(t, err)
+	require.False(t, ignored)
+
+	require.NoError(t, client.SetIgnoreCommands(context.Background(), torrent, true))
+	require.Equal(t, 1, ignore)
+	ignored, err = client.IgnoresCommands(context.Background(), torrent)
+	require.NoError(t, err)
+	require.True(t, ignored)
+
+	require.NoError(t, client.SetIgnoreCommands(context.Background(), torrent, false))
+	require.Equal(t, 0, ignore)
+}
+
+func TestConnectionType(t *testing.T) {
+	connection := "leech"
+	client := newTestClient(t, map[string]interface{}{
+		"d.connection_current": func() interface{} { return connection },
+		"d.connection_current.set": func(params []interface{}) interface{} {
+			connection = params[1].(string)
+			return 0
+		},
+	})
+	torrent := Torrent{Hash: testHash}
+
+	require.NoError(t, client.SetConnectionType(context.Background(), torrent, ConnectionInitialSeed))
+	got, err := client.ConnectionType(context.Background(), torrent)
+	require.NoError(t, err)
+	require.Equal(t, ConnectionInitialSeed, got)
+
+	require.EqualError(t, client.SetConnectionType(context.Background(), torrent, "superseed"), `invalid connection type "superseed"`)
+	require.Equal(t, "initial_seed", connection)
+}
+
+func TestCompareAndSetLabel(t *testing.T) {
+	label := "tv"
+	client := newTestClient(t, map[string]interface{}{
+		"d.custom1": func() interface{} { return label },
+		"d.custom1.set": func(params []interface{}) interface{} {
+			label = params[1].(string)
+			return 0
+		},
+	})
+
+	swapped, err := client.CompareAndSetLabel(context.Background(), Torrent{Hash: testHash}, "movies", "done")
+	require.NoError(t, err)
+	require.False(t, swapped)
+	require.Equal(t, "tv", label)
+
+	swapped, err = client.CompareAndSetLabel(context.Background(), Torrent{Hash: testHash}, "tv", "done")
+	require.NoError(t, err)
+	require.True(t, swapped)
+	require.Equal(t, "done", label)
+}
+
+func TestLabels(t *testing.T) {
+	label := "tv, hd,,"
+	responses := map[string]interface{}{
+		"d.custom1": func() interface{} { return label },
+		"d.custom1.set": func(params []interface{}) interface{} {
+			label = params[1].(string)
+			return 0
+		},
+	}
+	client := newTestClient(t, responses)
+	torrent := Torrent{Hash: testHash}
+
+	labels, err := client.GetLabels(context.Background(), torrent)
+	require.NoError(t, err)
+	require.Equal(t, []string{"tv", "hd"}, labels)
+
+	require.NoError(t, client.AddLabel(context.Background(), torrent, "new"))
+	require.Equal(t, "tv,hd,new", label)
+	require.NoError(t, client.AddLabel(context.Background(), torrent, "hd"))
+	require.Equal(t, "tv,hd,new", label)
+
+	require.NoError(t, client.RemoveLabel(context.Background(), torrent, "tv"))
+	require.Equal(t, "hd,new", label)
+
+	label = ""
+	labels, err = client.GetLabels(context.Background(), torrent)
+	require.NoError(t, err)
+	require.Empty(t, labels)
+
+	client.cfg.LabelDelimiter = "|"
+	label = "a|b"
+	require.NoError(t, client.RemoveLabel(context.Background(), torrent, "a"))
+	require.Equal(t, "b", label)
+}
+
+func TestTiedFile(t *testing.T) {
+	tied := "/watch/ubuntu.torrent"
+	client := newTestClient(t, map[string]interface{}{
+		"d.tied_to_file": func() interface{} { return tied },
+		"d.tied_to_file.set": func(params []interface{}) interface{} {
+			require.Equal(t, []interface{}{testHash, ""}, params)
+			tied = ""
+			return 0
+		},
+	})
+	torrent := Torrent{Hash: testHash}
+
+	file, err := client.TiedFile(context.Background(), torrent)
+	require.NoError(t, err)
+	require.Equal(t, "/watch/ubuntu.torrent", file)
+
+	require.NoError(t, client.Untie(context.Background(), torrent))
+
+	file, err = client.TiedFile(context.Background(), torrent)
+	require.NoError(t, err)
+	require.Empty(t, file)
+}
+
+func TestFilePath(t *testing.T) {
+	for _, tt := range []struct {
+		name     string
+		basePath string
+		dir      string
+		multi    int
+		want     string
+	}{
+		{name: "single file", basePath: "/downloads/ubuntu.iso", dir: "/downloads", want: "/downloads/ubuntu.iso"},
+		{name: "closed single file", dir: "/downloads", want: "/downloads/ubuntu.iso"},
+		{name: "multi file", basePath: "/downloads/ubuntu", dir: "/downloads/ubuntu", multi: 1, want: "/downloads/ubuntu/disc/ubuntu.iso"},
+		{name: "closed multi file", dir: "/downloads/ubuntu", multi: 1, want: "/downloads/ubuntu/disc/ubuntu.iso"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			client := newTestClient(t, map[string]interface{}{
+				"system.multicall": []interface{}{
+					[]interface{}{tt.basePath}, []interface{}{tt.dir}, []interface{}{"ubuntu.iso"}, []interface{}{tt.multi},
+				},
+				"f.path": func(params []interface{}) interface{} {
+					require.Equal(t, []interface{}{testHash + ":f1"}, params)
+					return "disc/ubuntu.iso"
+				},
+			})
+
+			index := 0
+			if tt.multi == 1 {
+				index = 1
+			}
+			filePath, err := client.FilePath(context.Background(), Torrent{Hash: testHash}, index)
+			require.NoError(t, err)
+			require.Equal(t, tt.want, filePath)
+		})
+	}
+}
+
+func TestFreeDiskSpace(t *testing.T) {
+	client := newTestClient(t, map[string]interface{}{
+		"execute.capture": func(params []interface{}) interface{} {
+			require.Equal(t, []interface{}{"", "df", "-P", "-k", "--", "/downloads"}, params)
+			return "Filesystem     1024-blocks      Used Available Capacity Mounted on\n/dev/sda1        960302804 412017404 499431488      46% /downloads\n"
+		},
+	})
+
+	free, err := client.FreeDiskSpace(context.Background(), "/downloads")
+	require.NoError(t, err)
+	require.Equal(t, int64(499431488*1024), free)
+
+	_, err = parseDFAvailable("df: /nope: No such file or directory\n")
+	require.Error(t, err)
+}
+
+func TestGetFilesMany(t *testing.T) {
+	var calls []interface{}
+	client := newTestClient(t, map[string]interface{}{
+		"system.multicall": func(params []interface{}) interface{} {
+			calls = params[0].([]interface{})
+			return []interface{}{
+				[]interface{}{[]interface{}{
+					[]interface{}{"e01.mkv", 1024, 0, 1, 1, 0, 1},
+					[]interface{}{"e02.mkv", 1024, 1024, 0, 1, 1, 2},
+				}},
+				notFoundFault,
+			}
+		},
+	})
+
+	files, err := client.GetFilesMany(context.Background(), []Torrent{{Hash: testHashA}, {Hash: testHashB}})
+	require.NoError(t, err)
+	require.Len(t, calls, 2)
+	require.Equal(t, map[string][]File{
+		testHashA: {
+			{Path: "e01.mkv", Size: 1024, CompletedChunks: 1, TotalChunks: 1, RangeSecond: 1},
+			{Path: "e02.mkv", Size: 1024, Offset: 1024, TotalChunks: 1, RangeFirst: 1, RangeSecond: 2},
+		},
+	}, files)
+}
+
+func TestSetDirectory(t *testing.T) {
+	calls := map[string][]interface{}{}
+	record := func(method string) func(params []interface{}) interface{} {
+		return func(params []interface{}) interface{} {
+			calls[method] = params
+			return 0
+		}
+	}
+	client := newTestClient(t, map[string]interface{}{
+		"d.directory.set":      record("d.directory.set"),
+		"d.directory_base.set": record("d.directory_base.set"),
+	})
+	torrent := Torrent{Hash: testHash}
+
+	require.NoError(t, client.SetDirectory(context.Background(), torrent, "/new"))
+	require.NoError(t, client.SetDirectoryBase(context.Background(), torrent, "/new/Show"))
+	require.Equal(t, map[string][]interface{}{
+		"d.directory.set":      {testHash, "/new"},
+		"d.directory_base.set": {testHash, "/new/Show"},
+	}, calls)
+}
+
+func TestAvailability(t *testing.T) {
+	client := newTestClient(t, map[string]interface{}{"d.chunks_seen": "0201FF00"})
+
+	chunks, err := client.ChunksSeen(context.Background(), Torrent{Hash: testHash})
+	require.NoError(t, err)
+	require.Equal(t, []int{2, 1, 255, 0}, chunks)
+
+	copies, err := client.Availability(context.Background(), Torrent{Hash: testHash})
+	require.NoError(t, err)
+	require.Equal(t, 0.75, copies)
+
+	require.Equal(t, 1.5, availability([]int{1, 2, 1, 3}))
+	require.Equal(t, 2.0, availability([]int{2, 2}))
+	require.Zero(t, availability(nil))
+}
+
+func TestScrapeTotals(t *testing.T) {
+	client := newTestClient(t, map[string]interface{}{
+		"t.multicall": []interface{}{
+			[]interface{}{1700000000, 10, 4},
+			[]interface{}{1700000000, 5, -1},
+			// never scraped
+			[]interface{}{0, 0, 0},
+			[]interface{}{0, -1, -1},
+		},
+	})
+
+	seeders, leechers, err := client.ScrapeTotals(context.Background(), Torrent{Hash: testHash})
+	require.NoError(t, err)
+	require.Equal(t, 15, seeders)
+	require.Equal(t, 4, leechers)
+}
+
+func TestGetTrackers(t *testing.T) {
+	var params []interface{}
+	client := newTestClient(t, map[string]interface{}{
+		"t.multicall": func(p []interface{}) interface{} {
+			params = p
+			return []interface{}{
+				[]interface{}{"https://tracker.example.com/announce", 1, 10, 4, 1700000000, 1700000100, 1700001900, 300, 1800, 12, 0, 2},
+				// never announced to, nothing scheduled
+				[]interface{}{"udp://backup.example.com:6969", 0, 0, 0, 0, 0, 0, 0, 1800, 0, 7, 0},
+			}
+		},
+	})
+
+	trackers, err := client.GetTrackers(context.Background(), Torrent{Hash: testHash})
+	require.NoError(t, err)
+	require.Equal(t, []interface{}{
+		testHash, "",
+		"t.url=", "t.is_enabled=", "t.scrape_complete=", "t.scrape_incomplete=", "t.scrape_time_last=",
+		"t.activity_time_last=", "t.activity_time_next=", "t.min_interval=", "t.normal_interval=",
+		"t.success_counter=", "t.failed_counter=", "t.latest_event=",
+	}, params)
+	require.Equal(t, []Tracker{
+		{
+			URL:          "https://tracker.example.com/announce",
+			Enabled:      true,
+			Seeders:      10,
+			Leechers:     4,
+			LastScrape:   time.Unix(1700000000, 0),
+			LastAnnounce: time.Unix(1700000100, 0),
+			NextAnnounce: time.Unix(1700001900, 0),
+			MinInterval:  5 * time.Minute,
+			Interval:     30 * time.Minute,
+			SuccessCount: 12,
+			LastEvent:    TrackerEventStarted,
+		},
+		{URL: "udp://backup.example.com:6969", Interval: 30 * time.Minute, FailCount: 7},
+	}, trackers)
+	require.Equal(t, time.Unix(1700000400, 0), trackers[0].EarliestAnnounce())
+	require.True(t, trackers[1].EarliestAnnounce().IsZero())
+	require.True(t, trackers[1].NextAnnounce.IsZero())
+	require.Equal(t, "started", trackers[0].LastEvent.String())
+	require.Equal(t, "none", trackers[1].LastEvent.String())
+}
+
+func TestTorrentNotFound(t *testing.T) {
+	values := make([]interface{}, len(torrentFields))
+	for i := range values {
+		values[i] = notFoundFault
+	}
+	client := newTestClient(t, map[string]interface{}{
+		"system.multicall": func(params []interface{}) interface{} {
+			return values[:len(params[0].([]interface{}))]
+		},
+		"f.multicall": faultResponse(-501, "Could not find info-hash."),
+	})
+
+	_, err := client.GetTorrent(context.Background(), testHash)
+	require.ErrorIs(t, err, ErrTorrentNotFound)
+
+	_, err = client.GetStatus(context.Background(), Torrent{Hash: testHash})
+	require.ErrorIs(t, err, ErrTorrentNotFound)
+
+	_, err = client.GetFiles(context.Background(), Torrent{Hash: testHash})
+	require.ErrorIs(t, err, ErrTorrentNotFound)
+}
+
+func TestAddTorrentReader(t *testing.T) {
+	data := []byte("d4:infod4:name3:fooee")
+	var params []interface{}
+	client := newTestClient(t, map[string]interface{}{
+		"load.raw": func(p []interface{}) interface{} {
+			params = p
+			return 0
+		},
+	})
+
+	err := client.AddTorrentReader(context.Background(), bytes.NewReader(data), false, DLabel.SetValue("my-label"))
+	require.NoError(t, err)
+	require.Equal(t, []interface{}{"", data, `d.custom1.set="my-label"`}, params)
+
+	// the add time is only recorded when asked to
+	client.cfg.RecordAddTime = true
+	before := time.Now().Unix()
+	err = client.AddTorrentReader(context.Background(), bytes.NewReader(data), false)
+	require.NoError(t, err)
+	require.Len(t, params, 3)
+	var added int64
+	_, err = fmt.Sscanf(params[2].(string), "d.custom.set=addtime,%d", &added)
+	require.NoError(t, err)
+	require.GreaterOrEqual(t, added, before)
+}
+
+func TestAddFromPath(t *testing.T) {
+	calls := map[string][]interface{}{}
+	record := func(method string) func(p []interface{}) interface{} {
+		return func(p []interface{}) interface{} {
+			calls[method] = p
+			return 0
+		}
+	}
+	client := newTestClient(t, map[string]interface{}{
+		"load.normal": record("load.normal"),
+		"load.start":  record("load.start"),
+	})
+
+	err := client.AddFromPath(context.Background(), "/watch/stopped.torrent", false, DLabel.SetValue("my-label"))
+	require.NoError(t, err)
+	require.Equal(t, map[string][]interface{}{
+		"load.normal": {"", []byte("/watch/stopped.torrent"), `d.custom1.set="my-label"`},
+	}, calls)
+
+	err = client.AddFromPath(context.Background(), "/watch/started.torrent", true, DLabel.SetValue("other"), DDirectory.SetValue("/downloads"))
+	require.NoError(t, err)
+	require.Equal(t, []interface{}{"", []byte("/watch/started.torrent"), `d.custom1.set="other"`, `d.directory.set="/downloads"`}, calls["load.start"])
+}
+
+func TestAddWithDirectory(t *testing.T) {
+	var method string
+	var params []interface{}
+	record := func(m string) func(p []interface{}) interface{} {
+		return func(p []interface{}) interface{} {
+			method, params = m, p
+			return 0
+		}
+	}
+	client := newTestClient(t, map[string]interface{}{
+		"load.normal": record("load.normal"),
+		"load.start":  record("load.start"),
+	})
+
+	err := client.AddWithDirectory(context.Background(), "http://example.com/a.torrent", "/downloads/movies", false, DLabel.SetValue("my-label"))
+	require.NoError(t, err)
+	require.Equal(t, "load.normal", method)
+	require.Equal(t, []interface{}{"", []byte("http://example.com/a.torrent"), `d.directory.set="/downloads/movies"`, `d.custom1.set="my-label"`}, params)
+
+	// quotes and backslashes in the directory must not end the value early
+	err = client.AddWithDirectory(context.Background(), "http://example.com/b.torrent", `/downloads/"quoted" \dir`, true)
+	require.NoError(t, err)
+	require.Equal(t, "load.start", method)
+	require.Equal(t, []interface{}{"", []byte("http://example.com/b.torrent"), `d.directory.set="/downloads/\"quoted\" \\dir"`}, params)
+}
+
+func TestAddTorrents(t *testing.T) {
+	var calls []interface{}
+	client := newTestClient(t, map[string]interface{}{
+		"system.multicall": func(params []interface{}) interface{} {
+			calls = params[0].([]interface{})
+			return []interface{}{
+				[]interface{}{0},
+				faultValue(-503, "Info hash already used by another torrent."),
+				faultValue(-503, "Could not create download."),
+			}
+		},
+	})
+
+	data, err := os.ReadFile("testdata/ubuntu-24.10-desktop-amd64.iso.torrent")
+	require.NoError(t, err)
+
+	results, err := client.AddTorrents(context.Background(), []AddItem{
+		{Data: data, Start: true, ExtraArgs: []*FieldValue{DLabel.SetValue("my-label")}},
+		{URL: "magnet:?xt=urn:btih:" + testHashA},
+		{URL: "https://example.com/broken.torrent"},
+	})
+	require.NoError(t, err)
+	require.Len(t, results, 3)
+	require.Equal(t, AddResult{Hash: testHash}, results[0])
+	require.Equal(t, testHashA, results[1].Hash)
+	require.ErrorIs(t, results[1].Err, ErrTorrentExists)
+	require.Empty(t, results[2].Hash)
+	require.ErrorContains(t, results[2].Err, "Could not create download")
+
+	require.Len(t, calls, 3)
+	var methods []interface{}
+	for _, call := range calls {
+		methods = append(methods, call.(map[string]interface{})["methodName"])
+	}
+	require.Equal(t, []interface{}{"load.raw_start", "load.normal", "load.normal"}, methods)
+}
+
+func TestAddTorrentWithFileSelection(t *testing.T) {
+	var methods []string
+	var batch []interface{}
+	client := newTestClient(t, map[string]interface{}{
+		"load.raw": func() interface{} {
+			methods = append(methods, "load.raw")
+			return 0
+		},
+		"f.multicall": func() interface{} {
+			methods = append(methods, "f.multicall")
+			return []interface{}{
+				[]interface{}{"Show/e01.mkv", 2048, 0, 0, 4, 0, 0},
+				[]interface{}{"Show/e02.mkv", 2048, 2048, 0, 4, 0, 0},
+				[]interface{}{"Show/e03.mkv", 2048, 4096, 0, 4, 0, 0},
+			}
+		},
+		"system.multicall": func(params []interface{}) interface{} {
+			methods = append(methods, "system.multicall")
+			batch = params[0].([]interface{})
+			return []interface{}{[]interface{}{0}, []interface{}{0}, []interface{}{0}}
+		},
+		"d.start": func() interface{} {
+			methods = append(methods, "d.start")
+			return 0
+		},
+	})
+
+	data, err := os.ReadFile("testdata/ubuntu-24.10-desktop-amd64.iso.torrent")
+	require.NoError(t, err)
+
+	require.NoError(t, client.AddTorrentWithFileSelection(context.Background(), data, []int{1}, true))
+	require.Equal(t, []string{"load.raw", "f.multicall", "system.multicall", "d.start"}, methods)
+	require.Equal(t, []interface{}{
+		map[string]interface{}{"methodName": "f.priority.set", "params": []interface{}{testHash + ":f0", 0}},
+		map[string]interface{}{"methodName": "f.priority.set", "params": []interface{}{testHash + ":f2", 0}},
+		map[string]interface{}{"methodName": "d.update_priorities", "params": []interface{}{testHash}},
+	}, batch)
+
+	methods = nil
+	err = client.AddTorrentWithFileSelection(context.Background(), data, []int{3}, true)
+	require.EqualError(t, err, "invalid file index 3, the torrent has 3 files")
+	require.Equal(t, []string{"load.raw", "f.multicall"}, methods)
+
+	methods = nil
+	err = client.AddTorrentWithFileSelection(context.Background(), data, []int{-1}, false)
+	require.EqualError(t, err, "invalid file index -1")
+	require.Empty(t, methods)
+}
+
+func TestAddTorrentCancel(t *testing.T) {
+	// the server stalls without reading the request, as over a slow link
+	stalled := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-stalled
+	}))
+	t.Cleanup(srv.Close)
+	t.Cleanup(func() { close(stalled) })
+	client := NewClient(Config{Addr: srv.URL})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(100*time.Millisecond, cancel)
+
+	// large enough to fill the socket buffers, so the upload is in flight when ctx is cancelled
+	start := time.Now()
+	err := client.AddTorrent(ctx, bytes.Repeat([]byte{'x'}, 32<<20))
+	require.ErrorIs(t, err, ctx.Err())
+	require.ErrorIs(t, err, context.Canceled)
+	require.Less(t, time.Since(start), 2*time.Second)
+}
+
+func TestAddAndWait(t *testing.T) {
+	interval := addWaitInterval
+	addWaitInterval = time.Millisecond
+	t.Cleanup(func() { addWaitInterval = interval })
+	row := torrentRow(Torrent{Hash: testHash, Name: "name"})
+
+	t.Run("url", func(t *testing.T) {
+		var token, cleared string
+		polls := 0
+		client := newTestClient(t, map[string]interface{}{
+			"load.start": func(params []interface{}) interface{} {
+				require.Equal(t, []byte("https://example.com/some.torrent"), params[1])
+				last := params[len(params)-1].(string)
+				require.True(t, strings.HasPrefix(last, "d.custom.set=add_token,"), last)
+				token = strings.TrimPrefix(last, "d.custom.set=add_token,")
+				return 0
+			},
+			"d.multicall.filtered": func(params []interface{}) interface{} {
+				require.Equal(t, `equal={d.custom=add_token,cat="`+token+`"}`, params[2])
+				if polls++; polls < 3 {
+					return []interface{}{}
+				}
+				return []interface{}{row}
+			},
+			"d.custom.set": func(params []interface{}) interface{} {
+				require.Equal(t, "add_token", params[1])
+				cleared = params[2].(string)
+				return 0
+			},
+		})
+
+		torrent, err := client.AddAndWait(context.Background(), "https://example.com/some.torrent", true)
+		require.NoError(t, err)
+		require.Equal(t, testHash, torrent.Hash)
+		require.Equal(t, 3, polls)
+		require.Len(t, token, 32)
+		require.Empty(t, cleared)
+	})
+
+	t.Run("magnet", func(t *testing.T) {
+		polls := 0
+		client := newTestClient(t, map[string]interface{}{
+			"load.normal": func(params []interface{}) interface{} {
+				for _, arg := range params[2:] {
+					require.NotContains(t, arg, "add_token")
+				}
+				return 0
+			},
+			"system.multicall": func(params []interface{}) interface{} {
+				values := make([]interface{}, len(torrentFields))
+				for i := range values {
+					values[i] = notFoundFault
+					if polls > 0 {
+						values[i] = []interface{}{row[i]}
+					}
+				}
+				polls++
+				return values
+			},
+		})
+
+		torrent, err := client.AddAndWait(context.Background(), "magnet:?xt=urn:btih:"+testHash, false)
+		require.NoError(t, err)
+		require.Equal(t, testHash, torrent.Hash)
+		require.Equal(t, 2, polls)
+	})
+
+	t.Run("timeout", func(t *testing.T) {
+		client := newTestClient(t, map[string]interface{}{
+			"load.start":           0,
+			"d.multicall.filtered": []interface{}{},
+		})
+
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		_, err := client.AddAndWait(ctx, "https://example.com/some.torrent", true)
+		require.ErrorIs(t, err, context.DeadlineExceeded)
+	})
+}
+
+func TestTorrentExists(t *testing.T) {
+	client := newTestClient(t, map[string]interface{}{
+		"load.raw_start": faultResponse(-503, "Info hash already used by another torrent."),
+	})
+
+	err := client.AddTorrent(context.Background(), []byte("d4:infod4:name3:fooee"))
+	require.ErrorIs(t, err, ErrTorrentExists)
+}
+
+func TestInvalidHash(t *testing.T) {
+	client := newTestClient(t, map[string]interface{}{})
+
+	err := client.Delete(context.Background(), Torrent{Hash: "nope"})
+	require.ErrorIs(t, err, ErrInvalidHash)
+
+	_, err = client.GetTorrent(context.Background(), "nope")
+	require.ErrorIs(t, err, ErrInvalidHash)
+
+	err = client.SetLabelMany(context.Background(), []string{testHashA, "nope"}, "TestLabel")
+	require.ErrorIs(t, err, ErrInvalidHash)
+}
+
+func TestCallTyped(t *testing.T) {
+	client := newTestClient(t, map[string]interface{}{
+		"system.hostname": "seedbox",
+		"throttle.global_down.total": rawResponse(`<?xml version="1.0"?>
+<methodResponse><params><param><value><i8>5665497088</i8></value></param></params></methodResponse>`),
+		"download_list": []interface{}{testHashA, testHashB},
+	})
+
+	name, err := client.CallString(context.Background(), "system.hostname")
+	require.NoError(t, err)
+	require.Equal(t, "seedbox", name)
+
+	total, err := client.CallInt(context.Background(), "throttle.global_down.total")
+	require.NoError(t, err)
+	require.Equal(t, int64(5665497088), total)
+
+	hashes, err := client.CallStringSlice(context.Background(), "download_list", "", "main")
+	require.NoError(t, err)
+	require.Equal(t, []string{testHashA, testHashB}, hashes)
+
+	_, err = client.CallInt(context.Background(), "system.hostname")
+	require.EqualError(t, err, "system.hostname: expected int, got string (seedbox)")
+}
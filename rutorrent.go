@@ -0,0 +1,41 @@
+package rtorrent
+
+import (
+	"context"
+	"net/url"
+	"reflect"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// RuTorrentMetadata is the metadata ruTorrent stores in the custom fields of a torrent
+type RuTorrentMetadata struct {
+	// Added is when the torrent was added, from the "addtime" custom field (d.custom=addtime)
+	Added time.Time `rtorrent:"d.custom=addtime"`
+	// SeedingStarted is when the torrent finished downloading and started seeding,
+	// from the "seedingtime" custom field (d.custom=seedingtime)
+	SeedingStarted time.Time `rtorrent:"d.custom=seedingtime"`
+	// Label is the label of the torrent, from d.custom1. ruTorrent stores it URL-encoded,
+	// it is decoded unless it isn't valid URL encoding.
+	Label string `rtorrent:"d.custom1"`
+}
+
+// ruTorrentColumns are the fields fetched for RuTorrentMetadata
+var ruTorrentColumns = structColumns(reflect.TypeOf(RuTorrentMetadata{}))
+
+// RuTorrentMetadata returns the metadata ruTorrent stored for the torrent, fields ruTorrent never set are left empty
+func (r *Client) RuTorrentMetadata(ctx context.Context, t Torrent) (RuTorrentMetadata, error) {
+	var meta RuTorrentMetadata
+	values, err := r.fetchFields(ctx, t.Hash, columnFields(ruTorrentColumns))
+	if err != nil {
+		return meta, err
+	}
+	if err := decodeColumns("ruTorrent metadata", values, ruTorrentColumns, reflect.ValueOf(&meta).Elem()); err != nil {
+		return meta, errors.Wrap(err, "system.multicall XMLRPC call returned unexpected data")
+	}
+	if label, err := url.PathUnescape(meta.Label); err == nil {
+		meta.Label = label
+	}
+	return meta, nil
+}
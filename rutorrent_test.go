@@ -0,0 +1,31 @@
+package rtorrent
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRuTorrentMetadata(t *testing.T) {
+	client := newTestClient(t, map[string]interface{}{
+		"system.multicall": func(params []interface{}) interface{} {
+			var methods []interface{}
+			for _, call := range params[0].([]interface{}) {
+				call := call.(map[string]interface{})
+				methods = append(methods, call["methodName"], call["params"])
+			}
+			require.Equal(t, []interface{}{
+				"d.custom", []interface{}{testHash, "addtime"},
+				"d.custom", []interface{}{testHash, "seedingtime"},
+				"d.custom1", []interface{}{testHash},
+			}, methods)
+			return []interface{}{[]interface{}{"1700000000"}, []interface{}{""}, []interface{}{"TV%20Shows"}}
+		},
+	})
+
+	meta, err := client.RuTorrentMetadata(context.Background(), Torrent{Hash: testHash})
+	require.NoError(t, err)
+	require.Equal(t, RuTorrentMetadata{Added: time.Unix(1700000000, 0), Label: "TV Shows"}, meta)
+}
@@ -0,0 +1,464 @@
+package rtorrent
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/autobrr/go-rtorrent/xmlrpc"
+
+	"github.com/pkg/errors"
+)
+
+// DHTMode represents the DHT mode of rTorrent, see SetDHTMode
+type DHTMode string
+
+const (
+	// DHTModeDisable disables DHT, it can't be started again without restarting rTorrent
+	DHTModeDisable DHTMode = "disable"
+	// DHTModeOff stops DHT
+	DHTModeOff DHTMode = "off"
+	// DHTModeAuto starts DHT when a non-private torrent is active, and stops it when none is
+	DHTModeAuto DHTMode = "auto"
+	// DHTModeOn starts DHT
+	DHTModeOn DHTMode = "on"
+)
+
+// Valid reports whether the mode is one rTorrent accepts
+func (m DHTMode) Valid() bool {
+	switch m {
+	case DHTModeDisable, DHTModeOff, DHTModeAuto, DHTModeOn:
+		return true
+	}
+	return false
+}
+
+// SetDHTMode sets the global DHT mode, unknown modes are rejected without calling rTorrent
+func (r *Client) SetDHTMode(ctx context.Context, mode DHTMode) error {
+	if !mode.Valid() {
+		return errors.Errorf("invalid DHT mode %q", mode)
+	}
+	if _, err := r.xmlrpcClient.Call(ctx, "dht.mode.set", "", string(mode)); err != nil {
+		return errors.Wrap(err, "dht.mode.set XMLRPC call failed")
+	}
+	return nil
+}
+
+// DHTActive checks if DHT is currently running, as reported by dht.statistics
+func (r *Client) DHTActive(ctx context.Context) (bool, error) {
+	results, err := r.xmlrpcClient.Call(ctx, "dht.statistics")
+	if err != nil {
+		return false, errors.Wrap(err, "dht.statistics XMLRPC call failed")
+	}
+	v, err := firstResult("dht.statistics", results)
+	if err != nil {
+		return false, err
+	}
+	stats, ok := v.(map[string]interface{})
+	if !ok {
+		return false, unexpectedType("dht.statistics", "struct", v)
+	}
+	// the statistics only hold "dht": "disabled" while DHT isn't running
+	state, err := asString("dht.statistics", stats["dht"])
+	if err != nil {
+		return false, err
+	}
+	return state != "disabled", nil
+}
+
+// SetPeerExchange enables or disables peer exchange (PEX) globally
+func (r *Client) SetPeerExchange(ctx context.Context, enabled bool) error {
+	if _, err := r.xmlrpcClient.Call(ctx, "protocol.pex.set", "", boolArg(enabled)); err != nil {
+		return errors.Wrap(err, "protocol.pex.set XMLRPC call failed")
+	}
+	return nil
+}
+
+// PeerExchange checks if peer exchange (PEX) is enabled globally
+func (r *Client) PeerExchange(ctx context.Context) (bool, error) {
+	enabled, err := r.CallInt(ctx, "protocol.pex")
+	return enabled == 1, err
+}
+
+// PeerExchangeEnabled checks if peer exchange (PEX) is enabled for the torrent
+func (r *Client) PeerExchangeEnabled(ctx context.Context, t Torrent) (bool, error) {
+	results, err := r.callHash(ctx, DPeerExchange.Cmd(), t.Hash)
+	if err != nil {
+		return false, errors.Wrap(err, "d.peer_exchange XMLRPC call failed")
+	}
+	enabled, err := resultInt(DPeerExchange.Cmd(), results)
+	return enabled == 1, err
+}
+
+// SetPeerExchangeEnabled enables or disables peer exchange (PEX) for the torrent. Enabling it for a
+// private torrent returns ErrPrivateTorrent without changing it, private trackers forbid PEX.
+func (r *Client) SetPeerExchangeEnabled(ctx context.Context, t Torrent, enabled bool) error {
+	if enabled {
+		results, err := r.callHash(ctx, DIsPrivate.Cmd(), t.Hash)
+		if err != nil {
+			return errors.Wrap(err, "d.is_private XMLRPC call failed")
+		}
+		private, err := resultInt(DIsPrivate.Cmd(), results)
+		if err != nil {
+			return err
+		}
+		if private == 1 {
+			return errors.Wrap(ErrPrivateTorrent, t.Hash)
+		}
+	}
+	if _, err := r.callHash(ctx, "d.peer_exchange.set", t.Hash, boolArg(enabled)); err != nil {
+		return errors.Wrap(err, "d.peer_exchange.set XMLRPC call failed")
+	}
+	return nil
+}
+
+// MaxUploadSlots returns the global maximum number of peers uploaded to at once, 0 means unlimited
+func (r *Client) MaxUploadSlots(ctx context.Context) (int, error) {
+	return r.globalInt(ctx, "throttle.max_uploads")
+}
+
+// SetMaxUploadSlots sets the global maximum number of peers uploaded to at once, 0 means unlimited
+func (r *Client) SetMaxUploadSlots(ctx context.Context, n int) error {
+	return r.setGlobalSlots(ctx, "throttle.max_uploads.set", n)
+}
+
+// MaxDownloadSlots returns the global maximum number of peers downloaded from at once, 0 means unlimited
+func (r *Client) MaxDownloadSlots(ctx context.Context) (int, error) {
+	return r.globalInt(ctx, "throttle.max_downloads")
+}
+
+// SetMaxDownloadSlots sets the global maximum number of peers downloaded from at once, 0 means unlimited
+func (r *Client) SetMaxDownloadSlots(ctx context.Context, n int) error {
+	return r.setGlobalSlots(ctx, "throttle.max_downloads.set", n)
+}
+
+// TorrentUploadSlots returns the maximum number of peers the torrent uploads to at once
+func (r *Client) TorrentUploadSlots(ctx context.Context, t Torrent) (int, error) {
+	results, err := r.callHash(ctx, "d.uploads_max", t.Hash)
+	if err != nil {
+		return 0, errors.Wrap(err, "d.uploads_max XMLRPC call failed")
+	}
+	return resultInt("d.uploads_max", results)
+}
+
+// SetTorrentUploadSlots sets the maximum number of peers the torrent uploads to at once
+func (r *Client) SetTorrentUploadSlots(ctx context.Context, t Torrent, n int) error {
+	return r.setTorrentSlots(ctx, t, "d.uploads_max.set", n)
+}
+
+// TorrentDownloadSlots returns the maximum number of peers the torrent downloads from at once
+func (r *Client) TorrentDownloadSlots(ctx context.Context, t Torrent) (int, error) {
+	results, err := r.callHash(ctx, "d.downloads_max", t.Hash)
+	if err != nil {
+		return 0, errors.Wrap(err, "d.downloads_max XMLRPC call failed")
+	}
+	return resultInt("d.downloads_max", results)
+}
+
+// SetTorrentDownloadSlots sets the maximum number of peers the torrent downloads from at once
+func (r *Client) SetTorrentDownloadSlots(ctx context.Context, t Torrent, n int) error {
+	return r.setTorrentSlots(ctx, t, "d.downloads_max.set", n)
+}
+
+// DownloadRateLimit returns the global download rate limit in bytes per second. rTorrent reports
+// an unlimited rate as 0, which is returned as limit 0 with unlimited set to true.
+func (r *Client) DownloadRateLimit(ctx context.Context) (limit int64, unlimited bool, err error) {
+	return r.rateLimit(ctx, "throttle.global_down.max_rate")
+}
+
+// UploadRateLimit returns the global upload rate limit in bytes per second. rTorrent reports
+// an unlimited rate as 0, which is returned as limit 0 with unlimited set to true.
+func (r *Client) UploadRateLimit(ctx context.Context) (limit int64, unlimited bool, err error) {
+	return r.rateLimit(ctx, "throttle.global_up.max_rate")
+}
+
+// DefaultDirectory returns the directory torrents added without one are downloaded to
+func (r *Client) DefaultDirectory(ctx context.Context) (string, error) {
+	return r.CallString(ctx, "directory.default")
+}
+
+// SetDefaultDirectory sets the directory torrents added without one are downloaded to,
+// torrents already added keep their directory
+func (r *Client) SetDefaultDirectory(ctx context.Context, dir string) error {
+	if dir == "" {
+		return errors.New("empty default directory")
+	}
+	if _, err := r.xmlrpcClient.Call(ctx, "directory.default.set", "", dir); err != nil {
+		return errors.Wrap(err, "directory.default.set XMLRPC call failed")
+	}
+	return nil
+}
+
+// SessionPath returns the directory rTorrent saves its session to, empty if it runs without a session
+func (r *Client) SessionPath(ctx context.Context) (string, error) {
+	return r.CallString(ctx, "session.path")
+}
+
+// HasSession checks if rTorrent saves its session, so the torrents are still loaded after a restart.
+// Without a session every torrent is gone once rTorrent restarts.
+func (r *Client) HasSession(ctx context.Context) (bool, error) {
+	path, err := r.SessionPath(ctx)
+	return path != "", err
+}
+
+// SetDownloadRateLimit sets the global download rate limit in bytes per second, 0 removes the limit
+func (r *Client) SetDownloadRateLimit(ctx context.Context, limit int64) error {
+	return r.setRateLimit(ctx, "throttle.global_down.max_rate.set", limit)
+}
+
+// SetUploadRateLimit sets the global upload rate limit in bytes per second, 0 removes the limit
+func (r *Client) SetUploadRateLimit(ctx context.Context, limit int64) error {
+	return r.setRateLimit(ctx, "throttle.global_up.max_rate.set", limit)
+}
+
+// EncryptionOptions are the peer protocol encryption options of rTorrent, see SetEncryption.
+// No options at all is rTorrent's "none": encryption is neither offered nor accepted.
+type EncryptionOptions struct {
+	// AllowIncoming accepts encrypted incoming connections (allow_incoming)
+	AllowIncoming bool
+	// TryOutgoing encrypts outgoing connections (try_outgoing)
+	TryOutgoing bool
+	// Require refuses unencrypted connections (require)
+	Require bool
+	// RequireRC4 refuses connections which only encrypt the handshake, it needs Require (require_RC4)
+	RequireRC4 bool
+	// EnableRetry retries failed outgoing connections with the other encryption setting (enable_retry)
+	EnableRetry bool
+	// PreferPlaintext chooses plaintext when the peer offers both, it contradicts Require (prefer_plaintext)
+	PreferPlaintext bool
+}
+
+// encryptionOptions maps the options to their name in protocol.encryption.set
+var encryptionOptions = []struct {
+	name  string
+	field func(o *EncryptionOptions) *bool
+}{
+	{"allow_incoming", func(o *EncryptionOptions) *bool { return &o.AllowIncoming }},
+	{"try_outgoing", func(o *EncryptionOptions) *bool { return &o.TryOutgoing }},
+	{"require", func(o *EncryptionOptions) *bool { return &o.Require }},
+	{"require_RC4", func(o *EncryptionOptions) *bool { return &o.RequireRC4 }},
+	{"enable_retry", func(o *EncryptionOptions) *bool { return &o.EnableRetry }},
+	{"prefer_plaintext", func(o *EncryptionOptions) *bool { return &o.PreferPlaintext }},
+}
+
+// Validate checks the options don't contradict each other
+func (o EncryptionOptions) Validate() error {
+	if o.RequireRC4 && !o.Require {
+		return errors.New("encryption option require_RC4 needs require")
+	}
+	if o.PreferPlaintext && o.Require {
+		return errors.New("encryption options prefer_plaintext and require contradict each other")
+	}
+	return nil
+}
+
+// SetEncryption sets the peer protocol encryption options, invalid options are rejected without calling rTorrent.
+// The options apply to new connections. rTorrent has no command returning the options, they can't be read back.
+func (r *Client) SetEncryption(ctx context.Context, o EncryptionOptions) error {
+	if err := o.Validate(); err != nil {
+		return err
+	}
+	args := []interface{}{""}
+	for _, option := range encryptionOptions {
+		if *option.field(&o) {
+			args = append(args, option.name)
+		}
+	}
+	if len(args) == 1 {
+		args = append(args, "none")
+	}
+	if _, err := r.xmlrpcClient.Call(ctx, "protocol.encryption.set", args...); err != nil {
+		return errors.Wrap(err, "protocol.encryption.set XMLRPC call failed")
+	}
+	return nil
+}
+
+// ChokeSettings are the global unchoke slot settings of rTorrent, see SetChokeSettings.
+// The slots are shared by all the torrents, on top of the per torrent limits of SetTorrentUploadSlots.
+type ChokeSettings struct {
+	// MaxUploads is the number of peers uploaded to at once across all torrents, 0 means unlimited
+	// (throttle.max_uploads.global)
+	MaxUploads int
+	// MaxDownloads is the number of peers downloaded from at once across all torrents, 0 means unlimited
+	// (throttle.max_downloads.global)
+	MaxDownloads int
+	// UploadsDivider divides the upload rate limit into upload slots per torrent (throttle.max_uploads.div)
+	UploadsDivider int
+	// DownloadsDivider divides the download rate limit into download slots per torrent (throttle.max_downloads.div)
+	DownloadsDivider int
+
+	// UnchokedUploads is the number of peers currently uploaded to, it is ignored by SetChokeSettings
+	// (throttle.unchoked_uploads)
+	UnchokedUploads int
+	// UnchokedDownloads is the number of peers currently downloaded from, it is ignored by SetChokeSettings
+	// (throttle.unchoked_downloads)
+	UnchokedDownloads int
+}
+
+// chokeSettings maps the settings to their command, the read-only ones have no setter
+var chokeSettings = []struct {
+	method   string
+	readOnly bool
+	field    func(s *ChokeSettings) *int
+}{
+	{"throttle.max_uploads.global", false, func(s *ChokeSettings) *int { return &s.MaxUploads }},
+	{"throttle.max_downloads.global", false, func(s *ChokeSettings) *int { return &s.MaxDownloads }},
+	{"throttle.max_uploads.div", false, func(s *ChokeSettings) *int { return &s.UploadsDivider }},
+	{"throttle.max_downloads.div", false, func(s *ChokeSettings) *int { return &s.DownloadsDivider }},
+	{"throttle.unchoked_uploads", true, func(s *ChokeSettings) *int { return &s.UnchokedUploads }},
+	{"throttle.unchoked_downloads", true, func(s *ChokeSettings) *int { return &s.UnchokedDownloads }},
+}
+
+// ChokeSettings returns the global unchoke slot settings in a single system.multicall
+func (r *Client) ChokeSettings(ctx context.Context) (ChokeSettings, error) {
+	var s ChokeSettings
+	calls := make([]xmlrpc.Call, 0, len(chokeSettings))
+	for _, setting := range chokeSettings {
+		calls = append(calls, xmlrpc.Call{Method: setting.method})
+	}
+	results, err := r.xmlrpcClient.MulticallBatch(ctx, calls)
+	if err != nil {
+		return s, err
+	}
+	for i, setting := range chokeSettings {
+		if fault, ok := results[i].(xmlrpc.Fault); ok {
+			return s, errors.Wrap(fault, fmt.Sprintf("%s XMLRPC call failed", setting.method))
+		}
+		if *setting.field(&s), err = asInt(setting.method, results[i]); err != nil {
+			return s, err
+		}
+	}
+	return s, nil
+}
+
+// SetChokeSettings sets the global unchoke slot settings in a single system.multicall, negative values
+// are rejected without calling rTorrent. The read-only UnchokedUploads and UnchokedDownloads are ignored.
+func (r *Client) SetChokeSettings(ctx context.Context, s ChokeSettings) error {
+	calls := make([]xmlrpc.Call, 0, len(chokeSettings))
+	for _, setting := range chokeSettings {
+		if setting.readOnly {
+			continue
+		}
+		value := *setting.field(&s)
+		if value < 0 {
+			return errors.Errorf("invalid %s %d", setting.method, value)
+		}
+		calls = append(calls, xmlrpc.Call{Method: setting.method + ".set", Params: []interface{}{"", value}})
+	}
+	results, err := r.xmlrpcClient.MulticallBatch(ctx, calls)
+	if err != nil {
+		return err
+	}
+	for i, v := range results {
+		if fault, ok := v.(xmlrpc.Fault); ok {
+			return errors.Wrap(fault, fmt.Sprintf("%s XMLRPC call failed", calls[i].Method))
+		}
+	}
+	return nil
+}
+
+// ListenPort returns the port rTorrent listens on for peers, as actually bound within its port range
+func (r *Client) ListenPort(ctx context.Context) (int, error) {
+	return r.globalInt(ctx, "network.listen.port")
+}
+
+// SetPortRange sets the range of ports rTorrent may listen on for peers, from and to included.
+// rTorrent binds a port from the range when it (re)opens its listening socket.
+func (r *Client) SetPortRange(ctx context.Context, from, to int) error {
+	if from < 1 || to > 65535 || from > to {
+		return errors.Errorf("invalid port range %d-%d", from, to)
+	}
+	if _, err := r.xmlrpcClient.Call(ctx, "network.port_range.set", "", fmt.Sprintf("%d-%d", from, to)); err != nil {
+		return errors.Wrap(err, "network.port_range.set XMLRPC call failed")
+	}
+	return nil
+}
+
+// MemoryUsage returns the memory in bytes currently used by rTorrent for pieces, and the maximum it may use
+func (r *Client) MemoryUsage(ctx context.Context) (current, limit int64, err error) {
+	results, err := r.xmlrpcClient.MulticallBatch(ctx, []xmlrpc.Call{
+		{Method: "pieces.memory.current"},
+		{Method: "pieces.memory.max"},
+	})
+	if err != nil {
+		return 0, 0, err
+	}
+	for i, method := range []string{"pieces.memory.current", "pieces.memory.max"} {
+		if fault, ok := results[i].(xmlrpc.Fault); ok {
+			return 0, 0, errors.Wrap(fault, fmt.Sprintf("%s XMLRPC call failed", method))
+		}
+	}
+	if current, err = asInt64("pieces.memory.current", results[0]); err != nil {
+		return 0, 0, err
+	}
+	if limit, err = asInt64("pieces.memory.max", results[1]); err != nil {
+		return 0, 0, err
+	}
+	return current, limit, nil
+}
+
+// SetMemoryMax sets the maximum memory in bytes rTorrent may use for pieces
+func (r *Client) SetMemoryMax(ctx context.Context, bytes int64) error {
+	if bytes <= 0 {
+		return errors.Errorf("invalid memory limit %d", bytes)
+	}
+	if _, err := r.xmlrpcClient.Call(ctx, "pieces.memory.max.set", "", bytes); err != nil {
+		return errors.Wrap(err, "pieces.memory.max.set XMLRPC call failed")
+	}
+	return nil
+}
+
+// globalInt returns the value of a global integer setting
+func (r *Client) globalInt(ctx context.Context, method string) (int, error) {
+	n, err := r.CallInt(ctx, method)
+	return int(n), err
+}
+
+// rateLimit returns the value of a global rate limit, and whether it is unlimited
+func (r *Client) rateLimit(ctx context.Context, method string) (int64, bool, error) {
+	limit, err := r.CallInt(ctx, method)
+	if err != nil {
+		return 0, false, err
+	}
+	return limit, limit == 0, nil
+}
+
+// setRateLimit sets a global rate limit
+func (r *Client) setRateLimit(ctx context.Context, method string, limit int64) error {
+	if limit < 0 {
+		return errors.Errorf("invalid rate limit %d", limit)
+	}
+	if _, err := r.xmlrpcClient.Call(ctx, method, "", limit); err != nil {
+		return errors.Wrap(err, fmt.Sprintf("%s XMLRPC call failed", method))
+	}
+	return nil
+}
+
+// setGlobalSlots sets a global slot limit
+func (r *Client) setGlobalSlots(ctx context.Context, method string, n int) error {
+	if n < 0 {
+		return errors.Errorf("invalid slot count %d", n)
+	}
+	if _, err := r.xmlrpcClient.Call(ctx, method, "", n); err != nil {
+		return errors.Wrap(err, fmt.Sprintf("%s XMLRPC call failed", method))
+	}
+	return nil
+}
+
+// setTorrentSlots sets a slot limit of the torrent
+func (r *Client) setTorrentSlots(ctx context.Context, t Torrent, method string, n int) error {
+	if n < 0 {
+		return errors.Errorf("invalid slot count %d", n)
+	}
+	if _, err := r.callHash(ctx, method, t.Hash, n); err != nil {
+		return errors.Wrap(err, fmt.Sprintf("%s XMLRPC call failed", method))
+	}
+	return nil
+}
+
+// boolArg returns the value rTorrent expects for a boolean setting
+func boolArg(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
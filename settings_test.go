@@ -0,0 +1,258 @@
+package rtorrent
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDHTMode(t *testing.T) {
+	var mode interface{}
+	client := newTestClient(t, map[string]interface{}{
+		"dht.mode.set": func(params []interface{}) interface{} {
+			mode = params[1]
+			return 0
+		},
+		"dht.statistics": func() interface{} {
+			if mode == "off" {
+				return map[string]interface{}{"dht": "disabled"}
+			}
+			return map[string]interface{}{"dht": "active", "active": 1}
+		},
+	})
+
+	require.NoError(t, client.SetDHTMode(context.Background(), DHTModeOff))
+	require.Equal(t, "off", mode)
+	active, err := client.DHTActive(context.Background())
+	require.NoError(t, err)
+	require.False(t, active)
+
+	require.NoError(t, client.SetDHTMode(context.Background(), DHTModeOn))
+	active, err = client.DHTActive(context.Background())
+	require.NoError(t, err)
+	require.True(t, active)
+
+	err = client.SetDHTMode(context.Background(), "enabled")
+	require.EqualError(t, err, `invalid DHT mode "enabled"`)
+	require.Equal(t, "on", mode)
+}
+
+func TestPeerExchange(t *testing.T) {
+	pex := 1
+	client := newTestClient(t, map[string]interface{}{
+		"protocol.pex.set": func(params []interface{}) interface{} {
+			require.Equal(t, "", params[0])
+			pex = params[1].(int)
+			return 0
+		},
+		"protocol.pex": func() interface{} { return pex },
+	})
+
+	require.NoError(t, client.SetPeerExchange(context.Background(), false))
+	enabled, err := client.PeerExchange(context.Background())
+	require.NoError(t, err)
+	require.False(t, enabled)
+}
+
+func TestSlots(t *testing.T) {
+	settings := map[string]interface{}{}
+	set := func(name string) func(params []interface{}) interface{} {
+		return func(params []interface{}) interface{} {
+			settings[name] = params
+			return 0
+		}
+	}
+	client := newTestClient(t, map[string]interface{}{
+		"throttle.max_uploads":     50,
+		"throttle.max_uploads.set": set("throttle.max_uploads.set"),
+		"d.uploads_max.set":        set("d.uploads_max.set"),
+	})
+
+	slots, err := client.MaxUploadSlots(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, 50, slots)
+
+	require.NoError(t, client.SetMaxUploadSlots(context.Background(), 100))
+	require.Equal(t, []interface{}{"", 100}, settings["throttle.max_uploads.set"])
+
+	require.NoError(t, client.SetTorrentUploadSlots(context.Background(), Torrent{Hash: testHash}, 4))
+	require.Equal(t, []interface{}{testHash, 4}, settings["d.uploads_max.set"])
+
+	require.EqualError(t, client.SetMaxUploadSlots(context.Background(), -1), "invalid slot count -1")
+}
+
+func TestRateLimit(t *testing.T) {
+	client := newTestClient(t, map[string]interface{}{
+		"throttle.global_down.max_rate": 0,
+		"throttle.global_up.max_rate":   1024000,
+	})
+
+	limit, unlimited, err := client.DownloadRateLimit(context.Background())
+	require.NoError(t, err)
+	require.True(t, unlimited)
+	require.Zero(t, limit)
+
+	limit, unlimited, err = client.UploadRateLimit(context.Background())
+	require.NoError(t, err)
+	require.False(t, unlimited)
+	require.Equal(t, int64(1024000), limit)
+}
+
+func TestDefaultDirectory(t *testing.T) {
+	dir := "/downloads"
+	client := newTestClient(t, map[string]interface{}{
+		"directory.default": func() interface{} { return dir },
+		"directory.default.set": func(params []interface{}) interface{} {
+			dir = params[1].(string)
+			return 0
+		},
+	})
+
+	require.NoError(t, client.SetDefaultDirectory(context.Background(), "/data/torrents"))
+	got, err := client.DefaultDirectory(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, "/data/torrents", got)
+
+	require.EqualError(t, client.SetDefaultDirectory(context.Background(), ""), "empty default directory")
+}
+
+func TestHasSession(t *testing.T) {
+	session := "/config/session/"
+	client := newTestClient(t, map[string]interface{}{
+		"session.path": func() interface{} { return session },
+	})
+
+	has, err := client.HasSession(context.Background())
+	require.NoError(t, err)
+	require.True(t, has)
+
+	session = ""
+	has, err = client.HasSession(context.Background())
+	require.NoError(t, err)
+	require.False(t, has)
+}
+
+func TestEncryption(t *testing.T) {
+	var options []interface{}
+	client := newTestClient(t, map[string]interface{}{
+		"protocol.encryption.set": func(params []interface{}) interface{} {
+			options = params[1:]
+			return 0
+		},
+	})
+
+	require.NoError(t, client.SetEncryption(context.Background(), EncryptionOptions{AllowIncoming: true, Require: true, RequireRC4: true}))
+	require.Equal(t, []interface{}{"allow_incoming", "require", "require_RC4"}, options)
+
+	require.NoError(t, client.SetEncryption(context.Background(), EncryptionOptions{}))
+	require.Equal(t, []interface{}{"none"}, options)
+
+	err := client.SetEncryption(context.Background(), EncryptionOptions{Require: true, PreferPlaintext: true})
+	require.EqualError(t, err, "encryption options prefer_plaintext and require contradict each other")
+	require.EqualError(t, EncryptionOptions{RequireRC4: true}.Validate(), "encryption option require_RC4 needs require")
+}
+
+func TestPeerExchangeEnabled(t *testing.T) {
+	private, pex := 0, 0
+	client := newTestClient(t, map[string]interface{}{
+		"d.is_private":    func() interface{} { return private },
+		"d.peer_exchange": func() interface{} { return pex },
+		"d.peer_exchange.set": func(params []interface{}) interface{} {
+			pex = params[1].(int)
+			return 0
+		},
+	})
+	torrent := Torrent{Hash: testHash}
+
+	require.NoError(t, client.SetPeerExchangeEnabled(context.Background(), torrent, true))
+	enabled, err := client.PeerExchangeEnabled(context.Background(), torrent)
+	require.NoError(t, err)
+	require.True(t, enabled)
+
+	private = 1
+	err = client.SetPeerExchangeEnabled(context.Background(), torrent, true)
+	require.ErrorIs(t, err, ErrPrivateTorrent)
+
+	require.NoError(t, client.SetPeerExchangeEnabled(context.Background(), torrent, false))
+	enabled, err = client.PeerExchangeEnabled(context.Background(), torrent)
+	require.NoError(t, err)
+	require.False(t, enabled)
+}
+
+func TestChokeSettings(t *testing.T) {
+	var calls []interface{}
+	client := newTestClient(t, map[string]interface{}{
+		"system.multicall": func(params []interface{}) interface{} {
+			calls = params[0].([]interface{})
+			if len(calls) == 4 {
+				return []interface{}{[]interface{}{0}, []interface{}{0}, []interface{}{0}, []interface{}{0}}
+			}
+			return []interface{}{
+				[]interface{}{50}, []interface{}{0}, []interface{}{1}, []interface{}{1}, []interface{}{12}, []interface{}{3},
+			}
+		},
+	})
+
+	s, err := client.ChokeSettings(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, ChokeSettings{MaxUploads: 50, UploadsDivider: 1, DownloadsDivider: 1, UnchokedUploads: 12, UnchokedDownloads: 3}, s)
+
+	s.MaxUploads = 100
+	require.NoError(t, client.SetChokeSettings(context.Background(), s))
+	require.Equal(t, []interface{}{
+		map[string]interface{}{"methodName": "throttle.max_uploads.global.set", "params": []interface{}{"", 100}},
+		map[string]interface{}{"methodName": "throttle.max_downloads.global.set", "params": []interface{}{"", 0}},
+		map[string]interface{}{"methodName": "throttle.max_uploads.div.set", "params": []interface{}{"", 1}},
+		map[string]interface{}{"methodName": "throttle.max_downloads.div.set", "params": []interface{}{"", 1}},
+	}, calls)
+
+	err = client.SetChokeSettings(context.Background(), ChokeSettings{MaxDownloads: -1})
+	require.EqualError(t, err, "invalid throttle.max_downloads.global -1")
+}
+
+func TestPorts(t *testing.T) {
+	var portRange interface{}
+	client := newTestClient(t, map[string]interface{}{
+		"network.listen.port": 51413,
+		"network.port_range.set": func(params []interface{}) interface{} {
+			portRange = params[1]
+			return 0
+		},
+	})
+
+	port, err := client.ListenPort(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, 51413, port)
+
+	require.NoError(t, client.SetPortRange(context.Background(), 50000, 50010))
+	require.Equal(t, "50000-50010", portRange)
+
+	require.EqualError(t, client.SetPortRange(context.Background(), 50010, 50000), "invalid port range 50010-50000")
+	require.EqualError(t, client.SetPortRange(context.Background(), 0, 10), "invalid port range 0-10")
+}
+
+func TestMemory(t *testing.T) {
+	var memoryMax interface{} = int64(1 << 30)
+	client := newTestClient(t, map[string]interface{}{
+		"system.multicall": func() interface{} {
+			return []interface{}{[]interface{}{int64(512 << 20)}, []interface{}{memoryMax}}
+		},
+		"pieces.memory.max.set": func(params []interface{}) interface{} {
+			memoryMax = params[1]
+			return 0
+		},
+	})
+
+	current, limit, err := client.MemoryUsage(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, int64(512<<20), current)
+	require.Equal(t, int64(1<<30), limit)
+
+	require.NoError(t, client.SetMemoryMax(context.Background(), 4<<30))
+	_, limit, err = client.MemoryUsage(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, int64(4<<30), limit)
+
+	require.EqualError(t, client.SetMemoryMax(context.Background(), 0), "invalid memory limit 0")
+}
@@ -0,0 +1,110 @@
+package rtorrent
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// throttleScheduleInterval is how often ScheduleThrottle checks which window is active
+var throttleScheduleInterval = time.Minute
+
+// ThrottleWindow holds the global rate limits applied during a time of day, see ScheduleThrottle
+type ThrottleWindow struct {
+	// Start and End are the time of day the window starts and ends at, as the time since midnight.
+	// A window with End before Start spans midnight, e.g. from 22h to 6h.
+	Start time.Duration
+	End   time.Duration
+	// DownloadLimit and UploadLimit are the global rate limits in bytes per second, 0 is unlimited
+	DownloadLimit int64
+	UploadLimit   int64
+}
+
+// contains reports whether the time of day falls in the window
+func (w ThrottleWindow) contains(timeOfDay time.Duration) bool {
+	if w.Start <= w.End {
+		return timeOfDay >= w.Start && timeOfDay < w.End
+	}
+	return timeOfDay >= w.Start || timeOfDay < w.End
+}
+
+// ScheduleThrottle applies the global rate limits of the window matching the time of day, checking
+// every minute until stop is called or ctx is done. The first matching window of schedule applies,
+// the limits are removed outside of all windows. The time of day is that of the clock of the rTorrent
+// host, see ServerTime, in the local time zone of the client. The limits of the current window are
+// applied before returning; failures of later checks are logged and retried on the next check.
+func (r *Client) ScheduleThrottle(ctx context.Context, schedule []ThrottleWindow) (stop func(), err error) {
+	for _, w := range schedule {
+		if w.Start < 0 || w.Start >= 24*time.Hour || w.End < 0 || w.End > 24*time.Hour {
+			return nil, errors.Errorf("invalid throttle window %s-%s", w.Start, w.End)
+		}
+		if w.DownloadLimit < 0 || w.UploadLimit < 0 {
+			return nil, errors.Errorf("invalid throttle window limits %d/%d", w.DownloadLimit, w.UploadLimit)
+		}
+	}
+
+	current, err := r.applyThrottle(ctx, schedule, throttleUnapplied)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(throttleScheduleInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+			var err error
+			if current, err = r.applyThrottle(ctx, schedule, current); err != nil && ctx.Err() == nil {
+				r.logError(ctx, "throttle schedule", err)
+			}
+		}
+	}()
+	return func() {
+		cancel()
+		<-done
+	}, nil
+}
+
+// noThrottleWindow and throttleUnapplied are the indexes applyThrottle uses for the time outside of all
+// windows, and for nothing applied yet
+const (
+	noThrottleWindow  = -1
+	throttleUnapplied = -2
+)
+
+// applyThrottle sets the limits of the window active at the time of the rTorrent host, unless the window
+// at index current is active and was applied already. It returns the index of the window applied.
+func (r *Client) applyThrottle(ctx context.Context, schedule []ThrottleWindow, current int) (int, error) {
+	now, err := r.ServerTime(ctx)
+	if err != nil {
+		return current, err
+	}
+	now = now.Local()
+	timeOfDay := time.Duration(now.Hour())*time.Hour + time.Duration(now.Minute())*time.Minute + time.Duration(now.Second())*time.Second
+
+	active, limits := noThrottleWindow, ThrottleWindow{}
+	for i, w := range schedule {
+		if w.contains(timeOfDay) {
+			active, limits = i, w
+			break
+		}
+	}
+	if active == current {
+		return current, nil
+	}
+	if err := r.SetDownloadRateLimit(ctx, limits.DownloadLimit); err != nil {
+		return current, err
+	}
+	if err := r.SetUploadRateLimit(ctx, limits.UploadLimit); err != nil {
+		return current, err
+	}
+	return active, nil
+}
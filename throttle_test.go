@@ -0,0 +1,73 @@
+package rtorrent
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestScheduleThrottle(t *testing.T) {
+	interval := throttleScheduleInterval
+	throttleScheduleInterval = 5 * time.Millisecond
+	t.Cleanup(func() { throttleScheduleInterval = interval })
+
+	var mu sync.Mutex
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.Local)
+	var sets []interface{}
+	set := func(params []interface{}) interface{} {
+		mu.Lock()
+		defer mu.Unlock()
+		sets = append(sets, params[1])
+		return 0
+	}
+	client := newTestClient(t, map[string]interface{}{
+		"system.time": func() interface{} {
+			mu.Lock()
+			defer mu.Unlock()
+			return now.Unix()
+		},
+		"throttle.global_down.max_rate.set": set,
+		"throttle.global_up.max_rate.set":   set,
+	})
+	applied := func() []interface{} {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]interface{}{}, sets...)
+	}
+
+	stop, err := client.ScheduleThrottle(context.Background(), []ThrottleWindow{
+		{Start: 8 * time.Hour, End: 22 * time.Hour, DownloadLimit: 1 << 20, UploadLimit: 512 << 10},
+		{Start: 22 * time.Hour, End: 2 * time.Hour, DownloadLimit: 4 << 20},
+	})
+	require.NoError(t, err)
+	require.Equal(t, []interface{}{1 << 20, 512 << 10}, applied())
+
+	// the limits are only set again once another window is active
+	time.Sleep(20 * time.Millisecond)
+	require.Len(t, applied(), 2)
+
+	mu.Lock()
+	now = time.Date(2024, 1, 1, 23, 30, 0, 0, time.Local)
+	mu.Unlock()
+	require.Eventually(t, func() bool { return len(applied()) == 4 }, time.Second, 5*time.Millisecond)
+	require.Equal(t, []interface{}{4 << 20, 0}, applied()[2:])
+
+	mu.Lock()
+	now = time.Date(2024, 1, 2, 3, 0, 0, 0, time.Local)
+	mu.Unlock()
+	require.Eventually(t, func() bool { return len(applied()) == 6 }, time.Second, 5*time.Millisecond)
+	require.Equal(t, []interface{}{0, 0}, applied()[4:])
+
+	stop()
+	mu.Lock()
+	now = time.Date(2024, 1, 2, 12, 0, 0, 0, time.Local)
+	mu.Unlock()
+	time.Sleep(20 * time.Millisecond)
+	require.Len(t, applied(), 6)
+
+	_, err = client.ScheduleThrottle(context.Background(), []ThrottleWindow{{Start: 25 * time.Hour, End: time.Hour}})
+	require.EqualError(t, err, "invalid throttle window 25h0m0s-1h0m0s")
+}
@@ -1,37 +1,128 @@
 package xmlrpc
 
 import (
+	"bufio"
 	"bytes"
+	"compress/gzip"
 	"context"
 	"crypto/tls"
+	"crypto/x509"
+	"fmt"
 	"io"
 	"log"
+	"log/slog"
+	"net"
 	"net/http"
+	"net/url"
+	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/pkg/errors"
 )
 
-// Client implements a basic XMLRPC client
+const (
+	// DefaultTimeout is the timeout applied to calls whose context has no deadline
+	DefaultTimeout = 60 * time.Second
+	// DefaultMaxIdleConns is the default maximum number of idle connections kept open
+	DefaultMaxIdleConns = 100
+	// DefaultMaxIdleConnsPerHost is the default maximum number of idle connections kept open
+	// to rTorrent, every call goes to the same host so this is higher than net/http's default of 2
+	DefaultMaxIdleConnsPerHost = 16
+	// DefaultIdleConnTimeout is the default time an idle connection is kept open
+	DefaultIdleConnTimeout = 90 * time.Second
+	// DefaultUserAgent is the User-Agent header sent with every call unless Config.UserAgent is set
+	DefaultUserAgent = "go-rtorrent"
+
+	// gzipMinSize is the request body size from which bodies are compressed
+	gzipMinSize = 1024
+)
+
+// Client implements a basic XMLRPC client.
+// It is safe for concurrent use by multiple goroutines.
 type Client struct {
 	addr       string
 	httpClient *http.Client
+	timeout    time.Duration
+	// slots bounds the calls in flight, nil when unbounded
+	slots chan struct{}
+	// retries is the number of times a request which didn't reach rTorrent is sent again
+	retries int
+	// gzip is set while request bodies are compressed, it is cleared when the server rejects them
+	gzip atomic.Bool
 
 	BasicUser string
 	BasicPass string
 
-	log *log.Logger
+	bearerToken string
+	authorize   func(*http.Request) error
+
+	userAgent string
+	headers   http.Header
+
+	log     *log.Logger
+	logger  *slog.Logger
+	verbose bool
 }
 
 type Config struct {
 	Addr          string
 	TLSSkipVerify bool
+	// ClientCert is presented to the server for mutual TLS
+	ClientCert *tls.Certificate
+	// RootCAs verifies the server certificate instead of the system roots, e.g. for a private CA
+	RootCAs *x509.CertPool
 
 	BasicUser string
 	BasicPass string
+	// BearerToken is sent as "Authorization: Bearer <token>", it takes precedence over basic auth
+	BearerToken string
+	// Authorize is called with every request once the client has set its own headers, allowing
+	// any other authentication, e.g. tokens which rotate. An error aborts the call.
+	Authorize func(req *http.Request) error
+
+	// UserAgent is sent as the User-Agent header of every call, defaults to DefaultUserAgent
+	UserAgent string
+	// Headers are sent with every call, e.g. for an authenticating proxy. They cannot override
+	// the headers set by the client itself, such as Content-Type or basic auth.
+	// Their values are redacted from verbose logs.
+	Headers map[string]string
 
 	Log *log.Logger
+	// Logger receives structured records of every call, it takes precedence over Log
+	Logger *slog.Logger
+	// Verbose additionally logs the full request and response XML of every call
+	Verbose bool
+
+	// Timeout bounds calls whose context has no deadline, defaults to DefaultTimeout.
+	// A context deadline always takes precedence.
+	Timeout time.Duration
+
+	// MaxIdleConns limits the idle connections kept open, defaults to DefaultMaxIdleConns
+	MaxIdleConns int
+	// MaxIdleConnsPerHost limits the idle connections kept open to rTorrent, defaults to DefaultMaxIdleConnsPerHost
+	MaxIdleConnsPerHost int
+	// IdleConnTimeout is how long an idle connection is kept open, defaults to DefaultIdleConnTimeout
+	IdleConnTimeout time.Duration
+
+	// Proxy is the HTTP, HTTPS or SOCKS5 (socks5:// scheme) proxy calls go through.
+	// Defaults to the proxy configured by the HTTP_PROXY, HTTPS_PROXY and NO_PROXY environment variables.
+	Proxy *url.URL
 
+	// Gzip compresses large request bodies and asks for compressed responses.
+	// Compression is turned off for the client if the server rejects a compressed request.
+	Gzip bool
+
+	// MaxConcurrentRequests bounds the calls in flight at once, further calls block until
+	// a call completes or their context is done. Zero means unbounded.
+	MaxConcurrentRequests int
+
+	// Retries is the number of times a request is sent again when it didn't reach rTorrent:
+	// the connection couldn't be established, or a proxy answered 502 or 503. Requests which
+	// reached rTorrent are never sent again, as calls such as load.raw aren't idempotent.
+	Retries int
+
+	// Client replaces the default http.Client, the transport settings above are ignored when set
 	Client *http.Client
 }
 
@@ -41,16 +132,65 @@ func NewClient(cfg Config) *Client {
 		addr:      cfg.Addr,
 		BasicUser: cfg.BasicUser,
 		BasicPass: cfg.BasicPass,
+
+		bearerToken: cfg.BearerToken,
+		authorize:   cfg.Authorize,
+
+		timeout:   DefaultTimeout,
+		userAgent: DefaultUserAgent,
 		log:       log.New(io.Discard, "", log.LstdFlags),
+		logger:    cfg.Logger,
+		verbose:   cfg.Verbose,
+	}
+	if cfg.Timeout > 0 {
+		c.timeout = cfg.Timeout
+	}
+	if cfg.UserAgent != "" {
+		c.userAgent = cfg.UserAgent
+	}
+	if len(cfg.Headers) > 0 {
+		c.headers = make(http.Header, len(cfg.Headers))
+		for k, v := range cfg.Headers {
+			c.headers.Set(k, v)
+		}
+	}
+	if cfg.MaxConcurrentRequests > 0 {
+		c.slots = make(chan struct{}, cfg.MaxConcurrentRequests)
+	}
+	if cfg.Retries > 0 {
+		c.retries = cfg.Retries
+	}
+	c.gzip.Store(cfg.Gzip)
+	transport := &http.Transport{
+		Proxy:               http.ProxyFromEnvironment,
+		MaxIdleConns:        DefaultMaxIdleConns,
+		MaxIdleConnsPerHost: DefaultMaxIdleConnsPerHost,
+		IdleConnTimeout:     DefaultIdleConnTimeout,
+	}
+	if cfg.MaxIdleConns > 0 {
+		transport.MaxIdleConns = cfg.MaxIdleConns
 	}
-	transport := &http.Transport{}
-	if cfg.TLSSkipVerify {
-		transport = &http.Transport{
-			TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+	if cfg.MaxIdleConnsPerHost > 0 {
+		transport.MaxIdleConnsPerHost = cfg.MaxIdleConnsPerHost
+	}
+	if cfg.IdleConnTimeout > 0 {
+		transport.IdleConnTimeout = cfg.IdleConnTimeout
+	}
+	if cfg.Proxy != nil {
+		transport.Proxy = http.ProxyURL(cfg.Proxy)
+	}
+	if cfg.TLSSkipVerify || cfg.ClientCert != nil || cfg.RootCAs != nil {
+		transport.TLSClientConfig = &tls.Config{
+			InsecureSkipVerify: cfg.TLSSkipVerify,
+			RootCAs:            cfg.RootCAs,
+		}
+		if cfg.ClientCert != nil {
+			transport.TLSClientConfig.Certificates = []tls.Certificate{*cfg.ClientCert}
 		}
 	}
 
-	c.httpClient = &http.Client{Transport: transport, Timeout: 60 * time.Second}
+	// the timeout is applied per call through the context, see Call
+	c.httpClient = &http.Client{Transport: transport}
 
 	if cfg.Client != nil {
 		c.httpClient = cfg.Client
@@ -65,42 +205,396 @@ func NewClient(cfg Config) *Client {
 }
 
 // NewClientWithHTTPClient returns a new instance of Client.
-// This allows you to use a custom http.Client setup for your needs.
-func NewClientWithHTTPClient(addr string, client *http.Client) *Client {
-	return &Client{
-		addr:       addr,
-		httpClient: client,
-	}
+// This allows you to use a custom http.Client setup for your needs,
+// while keeping the credentials and logger of cfg. cfg.TLSSkipVerify is ignored.
+func NewClientWithHTTPClient(cfg Config, client *http.Client) *Client {
+	cfg.Client = client
+	return NewClient(cfg)
+}
+
+// Close closes the idle connections kept open for reuse by the client.
+// Calls made after Close open new connections.
+func (c *Client) Close() {
+	c.httpClient.CloseIdleConnections()
 }
 
 // Call calls the method with "name" with the given args
-// Returns the result, and an error for communication errors
+// Returns the result, and an error for communication errors.
+// A fault returned by the server is reported as an error wrapping the Fault.
+//
+// The call is bounded by the deadline of ctx, or by the client's timeout if ctx has none.
 func (c *Client) Call(ctx context.Context, name string, args ...interface{}) (interface{}, error) {
-	data := bytes.NewBuffer(nil)
-	if err := Marshal(data, name, args...); err != nil {
-		return nil, errors.Wrap(err, "failed to marshal request")
+	_, _, val, err := c.call(ctx, name, false, args...)
+	return val, err
+}
+
+// CallRaw calls the method like Call, and additionally returns the request and response XML
+// exactly as sent and received, for debugging servers whose XMLRPC dialect differs.
+// The payloads are returned whenever they are available, even if the call failed.
+func (c *Client) CallRaw(ctx context.Context, name string, args ...interface{}) (requestXML, responseXML []byte, val interface{}, err error) {
+	return c.call(ctx, name, true, args...)
+}
+
+// call performs a call, keeping the raw payloads if asked to
+func (c *Client) call(ctx context.Context, name string, raw bool, args ...interface{}) (requestXML, responseXML []byte, val interface{}, err error) {
+	if _, ok := ctx.Deadline(); !ok && c.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.timeout)
+		defer cancel()
+	}
+
+	if c.slots != nil {
+		select {
+		case c.slots <- struct{}{}:
+			defer func() { <-c.slots }()
+		case <-ctx.Done():
+			return nil, nil, nil, errors.Wrap(ctx.Err(), "waiting for a request slot failed")
+		}
+	}
+
+	start := time.Now()
+	var resp *http.Response
+	if c.streamable(raw, args) {
+		resp, err = c.postStream(ctx, name, args)
+	} else {
+		data := bytes.NewBuffer(nil)
+		if err := Marshal(data, name, args...); err != nil {
+			return nil, nil, nil, errors.Wrap(err, "failed to marshal request")
+		}
+		if raw {
+			requestXML = data.Bytes()
+		}
+
+		compressed := c.gzip.Load() && data.Len() >= gzipMinSize
+		resp, err = c.postRetrying(ctx, name, data.Bytes(), compressed)
+		if err == nil && compressed && rejectsGzip(resp.StatusCode) {
+			// the server doesn't understand compressed requests, stop sending them
+			resp.Body.Close()
+			c.gzip.Store(false)
+			resp, err = c.postRetrying(ctx, name, data.Bytes(), false)
+		}
+	}
+	if err != nil {
+		c.logCall(ctx, name, len(args), 0, time.Since(start), err)
+		return requestXML, nil, nil, errors.Wrap(err, "POST failed")
+	}
+	defer resp.Body.Close()
+
+	var body io.Reader = resp.Body
+	if resp.Header.Get("Content-Encoding") == "gzip" {
+		zr, err := gzip.NewReader(resp.Body)
+		if err != nil {
+			return requestXML, nil, nil, errors.Wrap(err, "reading compressed response failed")
+		}
+		defer zr.Close()
+		body = zr
+	}
+	if c.verbose || raw {
+		b, err := io.ReadAll(body)
+		if err != nil {
+			return requestXML, nil, nil, errors.Wrap(err, "reading response failed")
+		}
+		if c.verbose {
+			c.logResponse(ctx, name, resp.StatusCode, string(b))
+		}
+		if raw {
+			responseXML = b
+		}
+		body = bytes.NewReader(b)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		// e.g. an HTML error page of a proxy in front of rTorrent, don't try to parse it
+		err = newHTTPError(resp, body)
+		c.logCall(ctx, name, len(args), resp.StatusCode, time.Since(start), err)
+		return requestXML, responseXML, nil, err
+	}
+
+	_, val, fault, err := Unmarshal(body)
+	if fault != nil {
+		// keep the fault available through errors.As
+		err = errors.WithStack(*fault)
+	}
+	c.logCall(ctx, name, len(args), resp.StatusCode, time.Since(start), err)
+	return requestXML, responseXML, val, err
+}
+
+// retryBackoff is the wait before the first retry of a request, it grows linearly with each retry
+var retryBackoff = 100 * time.Millisecond
+
+// postRetrying sends the request like post, sending it again up to the client's retries
+// while it didn't reach rTorrent
+func (c *Client) postRetrying(ctx context.Context, name string, payload []byte, compressed bool) (*http.Response, error) {
+	for attempt := 0; ; attempt++ {
+		resp, err := c.post(ctx, name, payload, compressed)
+		if attempt >= c.retries || !notDelivered(resp, err) {
+			return resp, err
+		}
+		if resp != nil {
+			resp.Body.Close()
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(retryBackoff * time.Duration(attempt+1)):
+		}
+	}
+}
+
+// notDelivered reports whether the request certainly wasn't processed by rTorrent, so sending it
+// again is safe: the connection couldn't be established, or a proxy in front of rTorrent couldn't
+// reach it or is overloaded
+func notDelivered(resp *http.Response, err error) bool {
+	if err != nil {
+		var opErr *net.OpError
+		return errors.As(err, &opErr) && opErr.Op == "dial"
 	}
+	return resp.StatusCode == http.StatusBadGateway || resp.StatusCode == http.StatusServiceUnavailable
+}
+
+// streamBufferSize is the size of the writes of a streamed request, see postStream
+const streamBufferSize = 32 << 10
 
-	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.addr, data)
+// streamable reports whether the request can be sent while it is marshalled rather than being built
+// in memory first: it has a streamed argument, such as an io.Reader read to the end as base64, and
+// nothing needs the whole request, which is kept by CallRaw, logged in verbose mode, and sent again
+// for retries or when the server rejects compression
+func (c *Client) streamable(raw bool, args []interface{}) bool {
+	if raw || c.verbose || c.retries > 0 || c.gzip.Load() {
+		return false
+	}
+	for _, arg := range args {
+		if _, ok := arg.(io.Reader); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// postStream sends the request while it is marshalled, so only a small buffer of it is held in memory.
+// Its length isn't known upfront, it is sent with chunked transfer encoding.
+func (c *Client) postStream(ctx context.Context, name string, args []interface{}) (*http.Response, error) {
+	pr, pw := io.Pipe()
+	go func() {
+		// the transport closes pr once it stops reading, failing the writes if the request failed
+		bw := bufio.NewWriterSize(pw, streamBufferSize)
+		err := Marshal(bw, name, args...)
+		if err != nil {
+			err = errors.Wrap(err, "failed to marshal request")
+		} else {
+			err = bw.Flush()
+		}
+		pw.CloseWithError(err)
+	}()
+	return c.send(ctx, name, pr, false, "")
+}
+
+// post sends the request body, compressing it if asked to
+func (c *Client) post(ctx context.Context, name string, payload []byte, compressed bool) (*http.Response, error) {
+	body := payload
+	if compressed {
+		var buf bytes.Buffer
+		zw := gzip.NewWriter(&buf)
+		if _, err := zw.Write(payload); err != nil {
+			return nil, errors.Wrap(err, "compressing request failed")
+		}
+		if err := zw.Close(); err != nil {
+			return nil, errors.Wrap(err, "compressing request failed")
+		}
+		body = buf.Bytes()
+	}
+	return c.send(ctx, name, bytes.NewReader(body), compressed, string(payload))
+}
+
+// send posts the request body, payload is the uncompressed request logged in verbose mode
+func (c *Client) send(ctx context.Context, name string, body io.Reader, compressed bool, payload string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.addr, body)
 	if err != nil {
 		return nil, errors.Wrap(err, "creating request failed")
 	}
 
+	for k, v := range c.headers {
+		req.Header[k] = v
+	}
 	req.Header.Set("Content-Type", "text/xml")
+	req.Header.Set("User-Agent", c.userAgent)
+	if c.gzip.Load() {
+		// setting this ourselves disables the transport's transparent decoding, see Call
+		req.Header.Set("Accept-Encoding", "gzip")
+	}
+	if compressed {
+		req.Header.Set("Content-Encoding", "gzip")
+	}
+
+	if err := c.addAuth(req); err != nil {
+		return nil, errors.Wrap(err, "authorizing request failed")
+	}
+
+	if c.verbose {
+		c.logRequest(ctx, name, req.Header, payload)
+	}
+
+	return c.httpClient.Do(req)
+}
+
+// httpErrorBodySize is the length of the response body kept in an HTTPError
+const httpErrorBodySize = 512
+
+// HTTPError is returned when the server answers a call with a status other than 200 OK,
+// it holds the start of the response body to help find out what answered instead of rTorrent
+type HTTPError struct {
+	StatusCode int
+	Status     string
+	Body       string
+}
+
+func (e *HTTPError) Error() string {
+	if e.Body == "" {
+		return fmt.Sprintf("unexpected HTTP status %s", e.Status)
+	}
+	return fmt.Sprintf("unexpected HTTP status %s: %s", e.Status, e.Body)
+}
+
+// newHTTPError returns the HTTPError of the response, with the start of body
+func newHTTPError(resp *http.Response, body io.Reader) *HTTPError {
+	b, _ := io.ReadAll(io.LimitReader(body, httpErrorBodySize))
+	return &HTTPError{StatusCode: resp.StatusCode, Status: resp.Status, Body: strings.TrimSpace(string(b))}
+}
+
+// rejectsGzip reports whether the status is a server's likely answer to a compressed body it can't read
+func rejectsGzip(status int) bool {
+	return status == http.StatusUnsupportedMediaType || status == http.StatusBadRequest
+}
+
+// logCall records the outcome of a call
+func (c *Client) logCall(ctx context.Context, name string, args, status int, latency time.Duration, err error) {
+	if c.logger == nil {
+		c.log.Printf("xmlrpc: %s args=%d status=%d latency=%s err=%v", name, args, status, latency, err)
+		return
+	}
+	attrs := []slog.Attr{
+		slog.String("method", name),
+		slog.Int("args", args),
+		slog.Int("status", status),
+		slog.Duration("latency", latency),
+	}
+	level := slog.LevelDebug
+	if err != nil {
+		level = slog.LevelError
+		attrs = append(attrs, slog.Any("error", err))
+	}
+	c.logger.LogAttrs(ctx, level, "xmlrpc call", attrs...)
+}
+
+// logRequest records the full request of a call, used in verbose mode
+func (c *Client) logRequest(ctx context.Context, name string, header http.Header, body string) {
+	if c.logger == nil {
+		c.log.Printf("xmlrpc: %s request headers=%v body=%s", name, c.redactHeaders(header), body)
+		return
+	}
+	c.logger.LogAttrs(ctx, slog.LevelDebug, "xmlrpc request",
+		slog.String("method", name),
+		slog.Any("headers", c.redactHeaders(header)),
+		slog.String("body", body),
+	)
+}
+
+// logResponse records the full response of a call, used in verbose mode
+func (c *Client) logResponse(ctx context.Context, name string, status int, body string) {
+	if c.logger == nil {
+		c.log.Printf("xmlrpc: %s response status=%d body=%s", name, status, body)
+		return
+	}
+	c.logger.LogAttrs(ctx, slog.LevelDebug, "xmlrpc response",
+		slog.String("method", name),
+		slog.Int("status", status),
+		slog.String("body", body),
+	)
+}
+
+// redactHeaders returns a copy of the headers with credentials and custom headers removed, suitable for logging
+func (c *Client) redactHeaders(h http.Header) http.Header {
+	redacted := h.Clone()
+	if redacted.Get("Authorization") != "" {
+		redacted.Set("Authorization", "REDACTED")
+	}
+	for k := range c.headers {
+		if redacted.Get(k) != "" {
+			redacted.Set(k, "REDACTED")
+		}
+	}
+	return redacted
+}
+
+// Call describes a single method invocation within a system.multicall batch
+type Call struct {
+	Method string
+	Params []interface{}
+}
 
-	c.addBasicAuth(req)
+// MulticallBatch executes the given calls in a single system.multicall request.
+// The returned slice holds one entry per call, in order: either the call's
+// result value or a Fault if that particular call failed.
+func (c *Client) MulticallBatch(ctx context.Context, calls []Call) ([]interface{}, error) {
+	structs := make([]interface{}, 0, len(calls))
+	for _, call := range calls {
+		params := call.Params
+		if params == nil {
+			params = []interface{}{}
+		}
+		structs = append(structs, map[string]interface{}{
+			"methodName": call.Method,
+			"params":     params,
+		})
+	}
 
-	resp, err := c.httpClient.Do(req)
+	result, err := c.Call(ctx, "system.multicall", structs)
 	if err != nil {
-		return nil, errors.Wrap(err, "POST failed")
+		return nil, errors.Wrap(err, "system.multicall XMLRPC call failed")
 	}
-	defer resp.Body.Close()
 
-	_, val, fault, err := Unmarshal(resp.Body)
-	if fault != nil {
-		err = errors.Errorf("Error: %v: %v", err, fault)
+	if params, ok := result.([]interface{}); ok && len(params) == 1 {
+		result = params[0]
 	}
-	return val, err
+	responses, ok := result.([]interface{})
+	if !ok {
+		return nil, errors.Errorf("system.multicall result isn't array: %T", result)
+	}
+	if len(responses) != len(calls) {
+		return nil, errors.Errorf("system.multicall returned %d results for %d calls", len(responses), len(calls))
+	}
+
+	results := make([]interface{}, len(responses))
+	for i, response := range responses {
+		switch v := response.(type) {
+		case []interface{}:
+			if len(v) != 1 {
+				return nil, errors.Errorf("system.multicall result %d (%s) has %d values, expected 1", i, calls[i].Method, len(v))
+			}
+			results[i] = v[0]
+		case map[string]interface{}:
+			fault, err := parseFault(v)
+			if err != nil {
+				return nil, errors.Wrapf(err, "system.multicall result %d (%s)", i, calls[i].Method)
+			}
+			results[i] = *fault
+		default:
+			return nil, errors.Errorf("system.multicall result %d (%s) has unexpected type %T", i, calls[i].Method, response)
+		}
+	}
+	return results, nil
+}
+
+func (c *Client) addAuth(req *http.Request) error {
+	if c.bearerToken != "" {
+		req.Header.Set("Authorization", "Bearer "+c.bearerToken)
+	} else {
+		c.addBasicAuth(req)
+	}
+	if c.authorize != nil {
+		return c.authorize(req)
+	}
+	return nil
 }
 
 func (c *Client) addBasicAuth(req *http.Request) {
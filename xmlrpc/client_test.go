@@ -1,3 +1,651 @@
 package xmlrpc
 
-// TODO: add tests
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"log/slog"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func newTestServer(t *testing.T, response string, requests *[]string) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		if requests != nil {
+			*requests = append(*requests, string(body))
+		}
+		w.Header().Set("Content-Type", "text/xml")
+		_, _ = io.WriteString(w, response)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// newSlowServer returns a server which only responds once the request is cancelled
+func newSlowServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.ReadAll(r.Body)
+		select {
+		case <-r.Context().Done():
+		case <-time.After(5 * time.Second):
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestMulticallBatch(t *testing.T) {
+	response := `<?xml version="1.0"?>
+<methodResponse><params><param><value><array><data>
+<value><array><data><value><string>rtorrent</string></value></data></array></value>
+<value><struct>
+<member><name>faultCode</name><value><int>-506</int></value></member>
+<member><name>faultString</name><value><string>Method 'nope' not defined</string></value></member>
+</struct></value>
+<value><array><data><value><i8>5665497088</i8></value></data></array></value>
+</data></array></value></param></params></methodResponse>`
+
+	var requests []string
+	srv := newTestServer(t, response, &requests)
+	client := NewClient(Config{Addr: srv.URL})
+
+	results, err := client.MulticallBatch(context.Background(), []Call{
+		{Method: "system.hostname"},
+		{Method: "nope"},
+		{Method: "d.size_bytes", Params: []interface{}{"HASH"}},
+	})
+	require.NoError(t, err)
+	require.Len(t, results, 3)
+	require.Equal(t, "rtorrent", results[0])
+	require.Equal(t, Fault{Code: -506, Message: "Method 'nope' not defined"}, results[1])
+	require.EqualValues(t, 5665497088, results[2])
+
+	require.Len(t, requests, 1)
+	_, params, _, err := Unmarshal(bytes.NewBufferString(requests[0]))
+	require.NoError(t, err)
+	require.Len(t, params, 1)
+	calls, ok := params[0].([]interface{})
+	require.True(t, ok)
+	require.Len(t, calls, 3)
+	require.Equal(t, map[string]interface{}{
+		"methodName": "d.size_bytes",
+		"params":     []interface{}{"HASH"},
+	}, calls[2])
+}
+
+func TestMulticallBatchLengthMismatch(t *testing.T) {
+	response := `<?xml version="1.0"?>
+<methodResponse><params><param><value><array><data>
+</data></array></value></param></params></methodResponse>`
+
+	srv := newTestServer(t, response, nil)
+	client := NewClient(Config{Addr: srv.URL})
+
+	_, err := client.MulticallBatch(context.Background(), []Call{{Method: "system.hostname"}})
+	require.Error(t, err)
+}
+
+func TestCallContextDeadline(t *testing.T) {
+	srv := newSlowServer(t)
+
+	client := NewClient(Config{Addr: srv.URL})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err := client.Call(ctx, "system.hostname")
+	require.Error(t, err)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	require.Less(t, time.Since(start), time.Second)
+}
+
+func TestCallTimeout(t *testing.T) {
+	srv := newSlowServer(t)
+
+	client := NewClient(Config{Addr: srv.URL, Timeout: 100 * time.Millisecond})
+
+	start := time.Now()
+	_, err := client.Call(context.Background(), "system.hostname")
+	require.Error(t, err)
+	require.Less(t, time.Since(start), time.Second)
+}
+
+func TestNewClientWithHTTPClient(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, pass, ok := r.BasicAuth()
+		if !ok || user != "user" || pass != "pass" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		_, _ = io.WriteString(w, `<?xml version="1.0"?>
+<methodResponse><params><param><value><string>rtorrent</string></value></param></params></methodResponse>`)
+	}))
+	t.Cleanup(srv.Close)
+
+	client := NewClientWithHTTPClient(Config{Addr: srv.URL, BasicUser: "user", BasicPass: "pass"}, &http.Client{})
+
+	result, err := client.Call(context.Background(), "system.hostname")
+	require.NoError(t, err)
+	require.Equal(t, []interface{}{"rtorrent"}, result)
+}
+
+func TestCallLogging(t *testing.T) {
+	response := `<?xml version="1.0"?>
+<methodResponse><params><param><value><string>rtorrent</string></value></param></params></methodResponse>`
+	srv := newTestServer(t, response, nil)
+
+	var buf bytes.Buffer
+	client := NewClient(Config{
+		Addr:      srv.URL,
+		BasicUser: "user",
+		BasicPass: "secret",
+		Log:       log.New(&buf, "", 0),
+		Verbose:   true,
+	})
+
+	result, err := client.Call(context.Background(), "system.hostname")
+	require.NoError(t, err)
+	require.Equal(t, []interface{}{"rtorrent"}, result)
+
+	out := buf.String()
+	require.Contains(t, out, "xmlrpc: system.hostname request")
+	require.Contains(t, out, "<methodName>system.hostname</methodName>")
+	require.Contains(t, out, "<string>rtorrent</string>")
+	require.Contains(t, out, "status=200")
+	require.Contains(t, out, "REDACTED")
+	require.NotContains(t, out, "Basic ")
+}
+
+func TestCallStructuredLogging(t *testing.T) {
+	response := `<?xml version="1.0"?>
+<methodResponse><params><param><value><string>rtorrent</string></value></param></params></methodResponse>`
+	srv := newTestServer(t, response, nil)
+
+	var buf bytes.Buffer
+	client := NewClient(Config{
+		Addr:   srv.URL,
+		Logger: slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug})),
+	})
+
+	_, err := client.Call(context.Background(), "system.hostname")
+	require.NoError(t, err)
+
+	var record map[string]interface{}
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &record))
+	require.Equal(t, "xmlrpc call", record["msg"])
+	require.Equal(t, "system.hostname", record["method"])
+	require.EqualValues(t, 200, record["status"])
+	require.Contains(t, record, "latency")
+	require.NotContains(t, record, "error")
+}
+
+func BenchmarkCallConcurrent(b *testing.B) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.ReadAll(r.Body)
+		_, _ = io.WriteString(w, `<?xml version="1.0"?>
+<methodResponse><params><param><value><string>rtorrent</string></value></param></params></methodResponse>`)
+	}))
+	b.Cleanup(srv.Close)
+
+	for _, bb := range []struct {
+		name        string
+		idlePerHost int
+		parallelism int
+	}{
+		{name: "stdlib idle per host", idlePerHost: 2, parallelism: 8},
+		{name: "default idle per host", idlePerHost: DefaultMaxIdleConnsPerHost, parallelism: 8},
+	} {
+		b.Run(bb.name, func(b *testing.B) {
+			client := NewClient(Config{Addr: srv.URL, MaxIdleConnsPerHost: bb.idlePerHost})
+			b.SetParallelism(bb.parallelism)
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				for pb.Next() {
+					if _, err := client.Call(context.Background(), "system.hostname"); err != nil {
+						b.Error(err)
+						return
+					}
+				}
+			})
+		})
+	}
+}
+
+func TestMaxConcurrentRequests(t *testing.T) {
+	var inFlight, maxInFlight int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.ReadAll(r.Body)
+		n := atomic.AddInt32(&inFlight, 1)
+		defer atomic.AddInt32(&inFlight, -1)
+		for {
+			m := atomic.LoadInt32(&maxInFlight)
+			if n <= m || atomic.CompareAndSwapInt32(&maxInFlight, m, n) {
+				break
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+		_, _ = io.WriteString(w, `<?xml version="1.0"?>
+<methodResponse><params><param><value><string>rtorrent</string></value></param></params></methodResponse>`)
+	}))
+	t.Cleanup(srv.Close)
+
+	client := NewClient(Config{Addr: srv.URL, MaxConcurrentRequests: 2})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := client.Call(context.Background(), "system.hostname")
+			require.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+	require.LessOrEqual(t, atomic.LoadInt32(&maxInFlight), int32(2))
+
+	// a call waiting for a slot gives up once its context is done
+	client.slots <- struct{}{}
+	client.slots <- struct{}{}
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	_, err := client.Call(ctx, "system.hostname")
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestCallGzip(t *testing.T) {
+	var encodings []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		encodings = append(encodings, r.Header.Get("Content-Encoding"))
+		var body io.Reader = r.Body
+		if r.Header.Get("Content-Encoding") == "gzip" {
+			zr, err := gzip.NewReader(r.Body)
+			require.NoError(t, err)
+			body = zr
+		}
+		_, _, _, err := Unmarshal(body)
+		require.NoError(t, err)
+
+		require.Equal(t, "gzip", r.Header.Get("Accept-Encoding"))
+		w.Header().Set("Content-Encoding", "gzip")
+		zw := gzip.NewWriter(w)
+		_, _ = io.WriteString(zw, `<?xml version="1.0"?>
+<methodResponse><params><param><value><i4>0</i4></value></param></params></methodResponse>`)
+		require.NoError(t, zw.Close())
+	}))
+	t.Cleanup(srv.Close)
+
+	client := NewClient(Config{Addr: srv.URL, Gzip: true})
+
+	result, err := client.Call(context.Background(), "load.raw", "", bytes.Repeat([]byte("x"), 4*gzipMinSize))
+	require.NoError(t, err)
+	require.Equal(t, []interface{}{0}, result)
+
+	// small requests are not worth compressing
+	_, err = client.Call(context.Background(), "system.hostname")
+	require.NoError(t, err)
+	require.Equal(t, []string{"gzip", ""}, encodings)
+}
+
+func TestCallGzipFallback(t *testing.T) {
+	var encodings []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		encodings = append(encodings, r.Header.Get("Content-Encoding"))
+		if r.Header.Get("Content-Encoding") != "" {
+			w.WriteHeader(http.StatusUnsupportedMediaType)
+			return
+		}
+		_, _ = io.ReadAll(r.Body)
+		_, _ = io.WriteString(w, `<?xml version="1.0"?>
+<methodResponse><params><param><value><i4>0</i4></value></param></params></methodResponse>`)
+	}))
+	t.Cleanup(srv.Close)
+
+	client := NewClient(Config{Addr: srv.URL, Gzip: true})
+
+	payload := bytes.Repeat([]byte("x"), 4*gzipMinSize)
+	for i := 0; i < 2; i++ {
+		result, err := client.Call(context.Background(), "load.raw", "", payload)
+		require.NoError(t, err)
+		require.Equal(t, []interface{}{0}, result)
+	}
+	require.Equal(t, []string{"gzip", "", ""}, encodings)
+}
+
+func TestCallRaw(t *testing.T) {
+	response := `<?xml version="1.0"?>
+<methodResponse><params><param><value><string>rtorrent</string></value></param></params></methodResponse>`
+	srv := newTestServer(t, response, nil)
+	client := NewClient(Config{Addr: srv.URL})
+
+	requestXML, responseXML, val, err := client.CallRaw(context.Background(), "system.hostname")
+	require.NoError(t, err)
+	require.Equal(t, []interface{}{"rtorrent"}, val)
+	require.Contains(t, string(requestXML), "<methodName>system.hostname</methodName>")
+	require.Equal(t, response, string(responseXML))
+}
+
+func TestUserAgent(t *testing.T) {
+	var userAgents []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userAgents = append(userAgents, r.UserAgent())
+		_, _ = io.WriteString(w, `<?xml version="1.0"?>
+<methodResponse><params><param><value><string>rtorrent</string></value></param></params></methodResponse>`)
+	}))
+	t.Cleanup(srv.Close)
+
+	_, err := NewClient(Config{Addr: srv.URL}).Call(context.Background(), "system.hostname")
+	require.NoError(t, err)
+	_, err = NewClient(Config{Addr: srv.URL, UserAgent: "my-app/1.0"}).Call(context.Background(), "system.hostname")
+	require.NoError(t, err)
+	require.Equal(t, []string{DefaultUserAgent, "my-app/1.0"}, userAgents)
+}
+
+func TestHeaders(t *testing.T) {
+	var header http.Header
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header = r.Header.Clone()
+		_, _ = io.WriteString(w, `<?xml version="1.0"?>
+<methodResponse><params><param><value><string>rtorrent</string></value></param></params></methodResponse>`)
+	}))
+	t.Cleanup(srv.Close)
+
+	var buf bytes.Buffer
+	client := NewClient(Config{
+		Addr:      srv.URL,
+		BasicUser: "user",
+		BasicPass: "pass",
+		Headers: map[string]string{
+			"CF-Access-Client-Id":     "id",
+			"CF-Access-Client-Secret": "secret",
+			"Content-Type":            "application/json",
+			"Authorization":           "Bearer nope",
+		},
+		Log:     log.New(&buf, "", 0),
+		Verbose: true,
+	})
+
+	_, err := client.Call(context.Background(), "system.hostname")
+	require.NoError(t, err)
+	require.Equal(t, "id", header.Get("CF-Access-Client-Id"))
+	require.Equal(t, "secret", header.Get("CF-Access-Client-Secret"))
+	require.Equal(t, "text/xml", header.Get("Content-Type"))
+	user, pass, ok := (&http.Request{Header: header}).BasicAuth()
+	require.True(t, ok)
+	require.Equal(t, "user", user)
+	require.Equal(t, "pass", pass)
+	require.NotContains(t, buf.String(), "secret")
+}
+
+func TestAuth(t *testing.T) {
+	var auths []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auths = append(auths, r.Header.Get("Authorization"))
+		_, _ = io.WriteString(w, `<?xml version="1.0"?>
+<methodResponse><params><param><value><string>rtorrent</string></value></param></params></methodResponse>`)
+	}))
+	t.Cleanup(srv.Close)
+
+	client := NewClient(Config{Addr: srv.URL, BasicUser: "user", BasicPass: "pass", BearerToken: "token"})
+	_, err := client.Call(context.Background(), "system.hostname")
+	require.NoError(t, err)
+
+	var n int
+	client = NewClient(Config{Addr: srv.URL, Authorize: func(req *http.Request) error {
+		n++
+		if n > 2 {
+			return errors.New("token expired")
+		}
+		req.Header.Set("Authorization", fmt.Sprintf("Bearer token-%d", n))
+		return nil
+	}})
+	for i := 0; i < 2; i++ {
+		_, err = client.Call(context.Background(), "system.hostname")
+		require.NoError(t, err)
+	}
+	_, err = client.Call(context.Background(), "system.hostname")
+	require.ErrorContains(t, err, "token expired")
+
+	require.Equal(t, []string{"Bearer token", "Bearer token-1", "Bearer token-2"}, auths)
+}
+
+// newClientCert returns a self-signed client certificate
+func newClientCert(t *testing.T) tls.Certificate {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "go-rtorrent"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+		IsCA:         true,
+
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	leaf, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key, Leaf: leaf}
+}
+
+func TestMutualTLS(t *testing.T) {
+	cert := newClientCert(t)
+	clientCAs := x509.NewCertPool()
+	clientCAs.AddCert(cert.Leaf)
+
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, `<?xml version="1.0"?>
+<methodResponse><params><param><value><string>rtorrent</string></value></param></params></methodResponse>`)
+	}))
+	srv.TLS = &tls.Config{ClientAuth: tls.RequireAndVerifyClientCert, ClientCAs: clientCAs}
+	srv.StartTLS()
+	t.Cleanup(srv.Close)
+
+	rootCAs := x509.NewCertPool()
+	rootCAs.AddCert(srv.Certificate())
+
+	_, err := NewClient(Config{Addr: srv.URL, RootCAs: rootCAs}).Call(context.Background(), "system.hostname")
+	require.Error(t, err)
+
+	result, err := NewClient(Config{Addr: srv.URL, RootCAs: rootCAs, ClientCert: &cert}).Call(context.Background(), "system.hostname")
+	require.NoError(t, err)
+	require.Equal(t, []interface{}{"rtorrent"}, result)
+}
+
+func TestProxy(t *testing.T) {
+	var proxied []string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proxied = append(proxied, r.URL.String())
+		_, _ = io.WriteString(w, `<?xml version="1.0"?>
+<methodResponse><params><param><value><string>rtorrent</string></value></param></params></methodResponse>`)
+	}))
+	t.Cleanup(proxy.Close)
+	proxyURL, err := url.Parse(proxy.URL)
+	require.NoError(t, err)
+
+	client := NewClient(Config{Addr: "http://rtorrent.internal/RPC2", Proxy: proxyURL})
+	result, err := client.Call(context.Background(), "system.hostname")
+	require.NoError(t, err)
+	require.Equal(t, []interface{}{"rtorrent"}, result)
+	require.Equal(t, []string{"http://rtorrent.internal/RPC2"}, proxied)
+}
+
+func TestClose(t *testing.T) {
+	closed := make(chan struct{}, 1)
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/xml")
+		_, _ = io.WriteString(w, `<?xml version="1.0"?><methodResponse><params><param><value><i4>0</i4></value></param></params></methodResponse>`)
+	}))
+	srv.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+		if state == http.StateClosed {
+			closed <- struct{}{}
+		}
+	}
+	srv.Start()
+	t.Cleanup(srv.Close)
+
+	client := NewClient(Config{Addr: srv.URL})
+	_, err := client.Call(context.Background(), "system.pid")
+	require.NoError(t, err)
+
+	client.Close()
+	select {
+	case <-closed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("idle connection was not closed")
+	}
+
+	_, err = client.Call(context.Background(), "system.pid")
+	require.NoError(t, err)
+}
+
+func TestHTTPError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.WriteHeader(http.StatusBadGateway)
+		_, _ = io.WriteString(w, "<html><body><h1>502 Bad Gateway</h1></body></html>\n")
+	}))
+	t.Cleanup(srv.Close)
+
+	client := NewClient(Config{Addr: srv.URL})
+	_, err := client.Call(context.Background(), "system.pid")
+	require.EqualError(t, err, "unexpected HTTP status 502 Bad Gateway: <html><body><h1>502 Bad Gateway</h1></body></html>")
+
+	var httpErr *HTTPError
+	require.ErrorAs(t, err, &httpErr)
+	require.Equal(t, http.StatusBadGateway, httpErr.StatusCode)
+}
+
+func TestRetries(t *testing.T) {
+	backoff := retryBackoff
+	retryBackoff = time.Millisecond
+	t.Cleanup(func() { retryBackoff = backoff })
+
+	statuses := []int{http.StatusServiceUnavailable, http.StatusBadGateway, http.StatusOK}
+	var requests atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.ReadAll(r.Body)
+		status := statuses[int(requests.Add(1)-1)%len(statuses)]
+		w.WriteHeader(status)
+		if status == http.StatusOK {
+			_, _ = io.WriteString(w, `<?xml version="1.0"?>
+<methodResponse><params><param><value><i4>0</i4></value></param></params></methodResponse>`)
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	t.Run("succeeds after retrying", func(t *testing.T) {
+		requests.Store(0)
+		client := NewClient(Config{Addr: srv.URL, Retries: 2})
+		result, err := client.Call(context.Background(), "load.raw", "")
+		require.NoError(t, err)
+		require.Equal(t, []interface{}{0}, result)
+		require.EqualValues(t, 3, requests.Load())
+	})
+
+	t.Run("gives up after the retries", func(t *testing.T) {
+		requests.Store(0)
+		client := NewClient(Config{Addr: srv.URL, Retries: 1})
+		_, err := client.Call(context.Background(), "load.raw", "")
+		var httpErr *HTTPError
+		require.ErrorAs(t, err, &httpErr)
+		require.Equal(t, http.StatusBadGateway, httpErr.StatusCode)
+		require.EqualValues(t, 2, requests.Load())
+	})
+
+	t.Run("doesn't retry by default", func(t *testing.T) {
+		requests.Store(0)
+		client := NewClient(Config{Addr: srv.URL})
+		_, err := client.Call(context.Background(), "load.raw", "")
+		require.Error(t, err)
+		require.EqualValues(t, 1, requests.Load())
+	})
+
+	t.Run("retries a refused connection", func(t *testing.T) {
+		l, err := net.Listen("tcp", "127.0.0.1:0")
+		require.NoError(t, err)
+		addr := "http://" + l.Addr().String()
+		require.NoError(t, l.Close())
+
+		client := NewClient(Config{Addr: addr, Retries: 2})
+		_, err = client.Call(context.Background(), "system.pid")
+		var opErr *net.OpError
+		require.ErrorAs(t, err, &opErr)
+		require.Equal(t, "dial", opErr.Op)
+	})
+}
+
+func TestCallStreamsReader(t *testing.T) {
+	type request struct {
+		contentLength int64
+		params        []interface{}
+	}
+	requests := make(chan request, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body := io.Reader(r.Body)
+		if r.Header.Get("Content-Encoding") == "gzip" {
+			zr, err := gzip.NewReader(r.Body)
+			require.NoError(t, err)
+			body = zr
+		}
+		_, params, _, err := Unmarshal(body)
+		if err != nil {
+			// a request aborted while it was sent
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		requests <- request{r.ContentLength, params}
+		_, _ = io.WriteString(w, `<?xml version="1.0"?>
+<methodResponse><params><param><value><i4>0</i4></value></param></params></methodResponse>`)
+	}))
+	t.Cleanup(srv.Close)
+
+	data := bytes.Repeat([]byte("torrent"), 64<<10)
+
+	// sent while it is marshalled, the length of the request isn't known
+	client := NewClient(Config{Addr: srv.URL})
+	_, err := client.Call(context.Background(), "load.raw", "", bytes.NewReader(data))
+	require.NoError(t, err)
+	req := <-requests
+	require.Equal(t, int64(-1), req.contentLength)
+	require.Equal(t, []interface{}{"", data}, req.params)
+
+	// compression may have to send the request again, it is built in memory
+	client = NewClient(Config{Addr: srv.URL, Gzip: true})
+	_, err = client.Call(context.Background(), "load.raw", "", bytes.NewReader(data))
+	require.NoError(t, err)
+	req = <-requests
+	require.Positive(t, req.contentLength)
+	require.Equal(t, []interface{}{"", data}, req.params)
+
+	_, err = NewClient(Config{Addr: srv.URL}).Call(context.Background(), "load.raw", bytes.NewReader(data), make(chan int))
+	require.ErrorContains(t, err, "failed to marshal request")
+}
@@ -7,6 +7,7 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"math"
 	"reflect"
 	"strconv"
 	"strings"
@@ -132,7 +133,7 @@ func (st *state) parseValue() (nv interface{}, e error) {
 		case "i8":
 			var i64 int64
 			i64, e = strconv.ParseInt(vn.Body, 10, 64)
-			nv = int(i64)
+			nv = i64
 		case "double":
 			nv, e = strconv.ParseFloat(vn.Body, 64)
 		case "dateTime.iso8601":
@@ -147,6 +148,11 @@ func (st *state) parseValue() (nv interface{}, e error) {
 		}
 		return
 
+	case "nil": // <nil/> or the <ex:nil/> extension
+		st.last = nil
+		e = st.p.DecodeElement(&vn, &se)
+		return
+
 	case "struct":
 		var name string
 		values := make(map[string]interface{}, 4)
@@ -317,7 +323,8 @@ func toXML(v interface{}, typ bool) (s string) {
 		reflect.Uint,
 		reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
 		if typ {
-			return fmt.Sprintf("<int>%v</int>", v)
+			tag := intTag(r)
+			return fmt.Sprintf("<%s>%v</%s>", tag, v, tag)
 		}
 		return fmt.Sprintf("%v", v)
 	case reflect.Uintptr:
@@ -398,6 +405,9 @@ func WriteXML(w io.Writer, v interface{}, typ bool) (err error) {
 		_, err = taggedWrite(w, []byte("base64"), dst)
 		return
 	}
+	if rd, ok := v.(io.Reader); ok {
+		return writeBase64(w, rd)
+	}
 	if tim, ok := v.(time.Time); ok {
 		_, err = taggedWriteString(w, "dateTime.iso8601", tim.Format(FullXMLRpcTime))
 		return
@@ -416,7 +426,8 @@ func WriteXML(w io.Writer, v interface{}, typ bool) (err error) {
 		reflect.Uint,
 		reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
 		if typ {
-			_, err = fmt.Fprintf(w, "<int>%v</int>", v)
+			tag := intTag(r)
+			_, err = fmt.Fprintf(w, "<%s>%v</%s>", tag, v, tag)
 			return err
 		}
 		_, err = fmt.Fprintf(w, "%v", v)
@@ -515,6 +526,37 @@ func WriteXML(w io.Writer, v interface{}, typ bool) (err error) {
 	return
 }
 
+// writeBase64 streams the contents of rd as a base64 value, without holding them in memory
+func writeBase64(w io.Writer, rd io.Reader) error {
+	if _, err := io.WriteString(w, "<base64>"); err != nil {
+		return err
+	}
+	enc := base64.NewEncoder(base64.StdEncoding, w)
+	if _, err := io.Copy(enc, rd); err != nil {
+		return err
+	}
+	if err := enc.Close(); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "</base64>")
+	return err
+}
+
+// intTag returns the tag for an integer value: int when it fits 32 bits, the i8 extension otherwise
+func intTag(r reflect.Value) string {
+	switch r.Kind() {
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if r.Uint() > math.MaxInt32 {
+			return "i8"
+		}
+	default:
+		if n := r.Int(); n > math.MaxInt32 || n < math.MinInt32 {
+			return "i8"
+		}
+	}
+	return "int"
+}
+
 func taggedWrite(w io.Writer, tag, inner []byte) (n int, err error) {
 	var j int
 	for _, elt := range [][]byte{[]byte("<"), tag, []byte(">"), inner,
@@ -641,25 +683,7 @@ func Unmarshal(r io.Reader) (name string, params []interface{}, fault *Fault, e
 				e = fmt.Errorf("fault not fault: %+v", v)
 				return
 			}
-			fault = &Fault{Code: -1, Message: ""}
-			code, ok := fmap["faultCode"]
-			if !ok {
-				e = fmt.Errorf("no faultCode in fault: %v", fmap)
-				return
-			}
-			fcode, ok := code.(int)
-			if !ok {
-				e = fmt.Errorf("faultCode not int? %v", code)
-				return
-			}
-			fault.Code = int(fcode)
-			msg, ok := fmap["faultString"]
-			if !ok {
-				e = fmt.Errorf("no faultString in fault: %v", fmap)
-				return
-			}
-			if fault.Message, ok = msg.(string); !ok {
-				e = fmt.Errorf("faultString not strin? %v", msg)
+			if fault, e = parseFault(fmap); e != nil {
 				return
 			}
 			e = st.checkLast("fault")
@@ -690,6 +714,31 @@ func Unmarshal(r io.Reader) (name string, params []interface{}, fault *Fault, e
 	return
 }
 
+// parseFault converts a decoded fault struct into a Fault
+func parseFault(fmap map[string]interface{}) (*Fault, error) {
+	fault := &Fault{Code: -1, Message: ""}
+	code, ok := fmap["faultCode"]
+	if !ok {
+		return nil, fmt.Errorf("no faultCode in fault: %v", fmap)
+	}
+	switch fcode := code.(type) {
+	case int:
+		fault.Code = fcode
+	case int64:
+		fault.Code = int(fcode)
+	default:
+		return nil, fmt.Errorf("faultCode not int? %v", code)
+	}
+	msg, ok := fmap["faultString"]
+	if !ok {
+		return nil, fmt.Errorf("no faultString in fault: %v", fmap)
+	}
+	if fault.Message, ok = msg.(string); !ok {
+		return nil, fmt.Errorf("faultString not strin? %v", msg)
+	}
+	return fault, nil
+}
+
 type errorStruct struct {
 	main    error
 	message string
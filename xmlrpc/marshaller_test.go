@@ -0,0 +1,57 @@
+package xmlrpc
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestUnmarshalI8(t *testing.T) {
+	response := `<?xml version="1.0"?>
+<methodResponse><params><param><value><array><data>
+<value><i8>5665497088</i8></value>
+<value><i8>-9223372036854775808</i8></value>
+<value><i4>42</i4></value>
+</data></array></value></param></params></methodResponse>`
+
+	_, val, fault, err := Unmarshal(bytes.NewBufferString(response))
+	require.NoError(t, err)
+	require.Nil(t, fault)
+	require.Equal(t, []interface{}{[]interface{}{int64(5665497088), int64(-9223372036854775808), 42}}, val)
+}
+
+func TestUnmarshalNil(t *testing.T) {
+	response := `<?xml version="1.0"?>
+<methodResponse xmlns:ex="http://ws.apache.org/xmlrpc/namespaces/extensions"><params><param><value><array><data>
+<value><nil/></value>
+<value><ex:nil/></value>
+<value><string>rtorrent</string></value>
+</data></array></value></param></params></methodResponse>`
+
+	_, val, fault, err := Unmarshal(bytes.NewBufferString(response))
+	require.NoError(t, err)
+	require.Nil(t, fault)
+	require.Equal(t, []interface{}{[]interface{}{nil, nil, "rtorrent"}}, val)
+}
+
+func TestMarshalI8(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, Marshal(&buf, "pieces.memory.max.set", "", int64(4294967296), 42))
+	require.Contains(t, buf.String(), "<i8>4294967296</i8>")
+	require.Contains(t, buf.String(), "<int>42</int>")
+
+	_, params, _, err := Unmarshal(&buf)
+	require.NoError(t, err)
+	require.Equal(t, []interface{}{"", int64(4294967296), 42}, params)
+}
+
+func TestMarshalReader(t *testing.T) {
+	var buf bytes.Buffer
+	data := bytes.Repeat([]byte("d4:infod4:name3:fooee"), 100)
+	require.NoError(t, Marshal(&buf, "load.raw", "", bytes.NewReader(data)))
+
+	_, params, _, err := Unmarshal(&buf)
+	require.NoError(t, err)
+	require.Equal(t, []interface{}{"", data}, params)
+}